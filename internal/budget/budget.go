@@ -0,0 +1,54 @@
+// Package budget checks spending against the configured cost budgets.
+package budget
+
+import (
+	"math"
+	"time"
+)
+
+// Limits are cost budgets in USD. Zero means no budget.
+type Limits struct {
+	Daily   float64
+	Weekly  float64 // Monday to Sunday
+	Project float64 // cumulative per project
+}
+
+// Spend is the cost in USD incurred against each budget.
+type Spend struct {
+	Day     float64
+	Week    float64
+	Project float64
+}
+
+// Any reports whether any budget is set.
+func (l Limits) Any() bool {
+	return l.Daily > 0 || l.Weekly > 0 || l.Project > 0
+}
+
+// Pct returns the highest consumption of the set budgets in percent, or 0
+// when no budget is set.
+func Pct(l Limits, s Spend) int {
+	pct := 0.0
+	for _, b := range []struct{ limit, spent float64 }{
+		{l.Daily, s.Day},
+		{l.Weekly, s.Week},
+		{l.Project, s.Project},
+	} {
+		if b.limit > 0 {
+			pct = max(pct, b.spent/b.limit*100)
+		}
+	}
+	return int(math.Floor(pct))
+}
+
+// DayStart returns the start of the local day of t.
+func DayStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// WeekStart returns the start of the local week of t, on Monday.
+func WeekStart(t time.Time) time.Time {
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return DayStart(t).AddDate(0, 0, -daysSinceMonday)
+}
@@ -0,0 +1,46 @@
+package budget
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPct(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		limits Limits
+		spend  Spend
+		want   int
+	}{
+		{name: "no budgets", spend: Spend{Day: 100}, want: 0},
+		{name: "daily", limits: Limits{Daily: 10}, spend: Spend{Day: 8.5}, want: 85},
+		{name: "highest wins", limits: Limits{Daily: 10, Weekly: 20, Project: 100}, spend: Spend{Day: 2, Week: 19, Project: 50}, want: 95},
+		{name: "over budget", limits: Limits{Project: 40}, spend: Spend{Project: 50}, want: 125},
+		{name: "rounds down", limits: Limits{Daily: 3}, spend: Spend{Day: 2.99}, want: 99},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := Pct(tt.limits, tt.spend); got != tt.want {
+				t.Errorf("Pct() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeekStart(t *testing.T) {
+	t.Parallel()
+
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local)
+	for _, day := range []time.Time{
+		monday,
+		time.Date(2026, 10, 14, 15, 30, 0, 0, time.Local), // Wednesday
+		time.Date(2026, 10, 18, 23, 59, 0, 0, time.Local), // Sunday
+	} {
+		if got := WeekStart(day); !got.Equal(monday) {
+			t.Errorf("WeekStart(%v) = %v, want %v", day, got, monday)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package burnrate records 5-hour quota usage samples and projects when the
+// quota will run out at the current rate.
+package burnrate
+
+import (
+	"math"
+	"time"
+
+	"github.com/fredrikaverpil/claudeline/internal/jsonfile"
+)
+
+const (
+	// window is the length of the 5-hour quota window. Older samples are
+	// dropped.
+	window = 5 * time.Hour
+	// lookback is how far back the burn rate is measured.
+	lookback = 30 * time.Minute
+	// minSpan is the shortest time span a burn rate is computed over.
+	minSpan = 2 * time.Minute
+	// minInterval rate limits samples with an unchanged percentage.
+	minInterval = 30 * time.Second
+	// maxSamples caps the history size.
+	maxSamples = 200
+	// resetTolerance allows for resets_at differing slightly between the
+	// stdin payload and the usage API within the same window.
+	resetTolerance = 10 * time.Minute
+)
+
+// Sample is a 5-hour quota utilization reading.
+type Sample struct {
+	Time     int64   `json:"t"`                   // Unix seconds
+	Pct      float64 `json:"pct"`                 // used percentage
+	ResetsAt int64   `json:"resets_at,omitempty"` // Unix seconds; 0 when unknown
+}
+
+// history is the on-disk format of the sample history.
+type history struct {
+	Samples []Sample `json:"samples"`
+}
+
+// Record adds s to the history at path and returns the samples of the
+// current quota window, oldest first. A new window (a different resets_at or
+// a drop in utilization) starts a new history.
+func Record(path string, s Sample) []Sample {
+	var samples []Sample
+	if h, err := jsonfile.Read[history](path); err == nil {
+		samples = h.Samples
+	}
+	samples, changed := add(samples, s)
+	if changed {
+		jsonfile.Write(path, history{Samples: samples})
+	}
+	return samples
+}
+
+// add appends s to samples, dropping samples from earlier windows, samples
+// older than the window and the oldest samples beyond maxSamples. It reports
+// whether samples changed.
+func add(samples []Sample, s Sample) ([]Sample, bool) {
+	if n := len(samples); n > 0 {
+		last := samples[n-1]
+		switch {
+		case s.Time < last.Time:
+			return samples, false // out of order; keep the history as is
+		case newWindow(last, s):
+			samples = nil
+		case s.Pct == last.Pct && time.Duration(s.Time-last.Time)*time.Second < minInterval:
+			return samples, false
+		}
+	}
+	samples = append(samples, s)
+
+	cutoff := s.Time - int64(window/time.Second)
+	start := 0
+	for start < len(samples) && samples[start].Time < cutoff {
+		start++
+	}
+	start = max(start, len(samples)-maxSamples)
+	return samples[start:], true
+}
+
+// newWindow reports whether s belongs to a later quota window than last.
+func newWindow(last, s Sample) bool {
+	if s.Pct < last.Pct {
+		return true
+	}
+	if s.ResetsAt == 0 || last.ResetsAt == 0 {
+		return false
+	}
+	return time.Duration(s.ResetsAt-last.ResetsAt)*time.Second > resetTolerance
+}
+
+// Project returns when utilization reaches 100% at the burn rate of the last
+// 30 minutes. It reports false when there is too little history or usage is
+// not growing.
+func Project(samples []Sample) (time.Time, bool) {
+	if len(samples) < 2 {
+		return time.Time{}, false
+	}
+	latest := samples[len(samples)-1]
+	first := latest
+	for _, s := range samples {
+		if time.Duration(latest.Time-s.Time)*time.Second <= lookback {
+			first = s
+			break
+		}
+	}
+	span := time.Duration(latest.Time-first.Time) * time.Second
+	if span < minSpan || latest.Pct <= first.Pct {
+		return time.Time{}, false
+	}
+	if latest.Pct >= 100 {
+		return time.Unix(latest.Time, 0), true
+	}
+	perSecond := (latest.Pct - first.Pct) / span.Seconds()
+	remaining := time.Duration(math.Round((100-latest.Pct)/perSecond)) * time.Second
+	return time.Unix(latest.Time, 0).Add(remaining), true
+}
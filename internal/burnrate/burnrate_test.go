@@ -0,0 +1,167 @@
+package burnrate
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAdd(t *testing.T) {
+	t.Parallel()
+
+	const reset = 100_000
+	tests := []struct {
+		name        string
+		samples     []Sample
+		s           Sample
+		want        []Sample
+		wantChanged bool
+	}{
+		{
+			name:        "first sample",
+			s:           Sample{Time: 1000, Pct: 10, ResetsAt: reset},
+			want:        []Sample{{Time: 1000, Pct: 10, ResetsAt: reset}},
+			wantChanged: true,
+		},
+		{
+			name:        "appends",
+			samples:     []Sample{{Time: 1000, Pct: 10, ResetsAt: reset}},
+			s:           Sample{Time: 1060, Pct: 11, ResetsAt: reset},
+			want:        []Sample{{Time: 1000, Pct: 10, ResetsAt: reset}, {Time: 1060, Pct: 11, ResetsAt: reset}},
+			wantChanged: true,
+		},
+		{
+			name:    "skips unchanged sample within interval",
+			samples: []Sample{{Time: 1000, Pct: 10, ResetsAt: reset}},
+			s:       Sample{Time: 1010, Pct: 10, ResetsAt: reset},
+			want:    []Sample{{Time: 1000, Pct: 10, ResetsAt: reset}},
+		},
+		{
+			name:    "skips out of order sample",
+			samples: []Sample{{Time: 1000, Pct: 10, ResetsAt: reset}},
+			s:       Sample{Time: 900, Pct: 12, ResetsAt: reset},
+			want:    []Sample{{Time: 1000, Pct: 10, ResetsAt: reset}},
+		},
+		{
+			name:        "tolerates resets_at jitter",
+			samples:     []Sample{{Time: 1000, Pct: 10, ResetsAt: reset}},
+			s:           Sample{Time: 1060, Pct: 11, ResetsAt: reset + 60},
+			want:        []Sample{{Time: 1000, Pct: 10, ResetsAt: reset}, {Time: 1060, Pct: 11, ResetsAt: reset + 60}},
+			wantChanged: true,
+		},
+		{
+			name:        "new window on later reset",
+			samples:     []Sample{{Time: 1000, Pct: 10, ResetsAt: reset}},
+			s:           Sample{Time: 1060, Pct: 11, ResetsAt: reset + 5*3600},
+			want:        []Sample{{Time: 1060, Pct: 11, ResetsAt: reset + 5*3600}},
+			wantChanged: true,
+		},
+		{
+			name:        "new window on utilization drop",
+			samples:     []Sample{{Time: 1000, Pct: 80}},
+			s:           Sample{Time: 1060, Pct: 2},
+			want:        []Sample{{Time: 1060, Pct: 2}},
+			wantChanged: true,
+		},
+		{
+			name:        "drops samples older than the window",
+			samples:     []Sample{{Time: 1000, Pct: 10}, {Time: 10_000, Pct: 20}},
+			s:           Sample{Time: 1000 + 5*3600 + 1, Pct: 30},
+			want:        []Sample{{Time: 10_000, Pct: 20}, {Time: 1000 + 5*3600 + 1, Pct: 30}},
+			wantChanged: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, changed := add(tt.samples, tt.s)
+			if changed != tt.wantChanged {
+				t.Errorf("add() changed = %v, want %v", changed, tt.wantChanged)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("add() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdd_maxSamples(t *testing.T) {
+	t.Parallel()
+
+	var samples []Sample
+	for i := range maxSamples + 10 {
+		samples, _ = add(samples, Sample{Time: int64(i * 60), Pct: float64(i) / 10})
+	}
+	if len(samples) != maxSamples {
+		t.Errorf("len(samples) = %d, want %d", len(samples), maxSamples)
+	}
+	if got, want := samples[len(samples)-1].Time, int64((maxSamples+9)*60); got != want {
+		t.Errorf("last sample time = %d, want %d", got, want)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "burnrate.json")
+	Record(path, Sample{Time: 1000, Pct: 10})
+	got := Record(path, Sample{Time: 1600, Pct: 15})
+	want := []Sample{{Time: 1000, Pct: 10}, {Time: 1600, Pct: 15}}
+	if !slices.Equal(got, want) {
+		t.Errorf("Record() = %v, want %v", got, want)
+	}
+}
+
+func TestProject(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		samples []Sample
+		want    int64 // Unix seconds; 0 means no projection
+	}{
+		{name: "no samples"},
+		{name: "single sample", samples: []Sample{{Time: 1000, Pct: 10}}},
+		{
+			name:    "span too short",
+			samples: []Sample{{Time: 1000, Pct: 10}, {Time: 1060, Pct: 12}},
+		},
+		{
+			name:    "flat usage",
+			samples: []Sample{{Time: 1000, Pct: 10}, {Time: 1600, Pct: 10}},
+		},
+		{
+			// 10% per 10 minutes, 80% left.
+			name:    "steady burn",
+			samples: []Sample{{Time: 1000, Pct: 10}, {Time: 1300, Pct: 15}, {Time: 1600, Pct: 20}},
+			want:    1600 + 80*60,
+		},
+		{
+			// Only the last 30 minutes count: 5% per 10 minutes.
+			name: "ignores samples before lookback",
+			samples: []Sample{
+				{Time: 0, Pct: 0}, {Time: 3600, Pct: 60}, {Time: 3600 + 1800, Pct: 90},
+				{Time: 7200, Pct: 90}, {Time: 7800, Pct: 95},
+			},
+			want: 7800 + 10*60,
+		},
+		{
+			name:    "already exhausted",
+			samples: []Sample{{Time: 1000, Pct: 90}, {Time: 1600, Pct: 100}},
+			want:    1600,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, ok := Project(tt.samples)
+			if ok != (tt.want != 0) {
+				t.Fatalf("Project() ok = %v, want %v", ok, tt.want != 0)
+			}
+			if ok && !got.Equal(time.Unix(tt.want, 0)) {
+				t.Errorf("Project() = %d, want %d", got.Unix(), tt.want)
+			}
+		})
+	}
+}
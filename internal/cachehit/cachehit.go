@@ -0,0 +1,68 @@
+// Package cachehit tracks each session's prompt cache efficiency across
+// turns: the share of input tokens read from the cache, and the run of
+// consecutive cache misses.
+package cachehit
+
+import (
+	"time"
+
+	"github.com/fredrikaverpil/claudeline/internal/sessionstore"
+)
+
+// Usage is one turn's input token breakdown, from stdin current_usage.
+type Usage struct {
+	Input      int `json:"input"`       // uncached input tokens
+	CacheRead  int `json:"cache_read"`  // tokens read from the cache
+	CacheWrite int `json:"cache_write"` // tokens written to the cache
+}
+
+// Miss reports whether the turn missed the prompt cache: nothing was read
+// from it, but the prompt was written to it.
+func (u Usage) Miss() bool {
+	return u.CacheRead == 0 && u.CacheWrite > 0
+}
+
+// Session is a session's cumulative input tokens since tracking started.
+type Session struct {
+	Input      int64 `json:"input"`
+	CacheRead  int64 `json:"cache_read"`
+	CacheWrite int64 `json:"cache_write"`
+	Misses     int   `json:"misses"` // consecutive cache misses up to the last turn
+	Last       Usage `json:"last"`   // last turn, to recognize re-renders
+}
+
+// Record adds the turn's usage to the session in the state file at path and
+// returns the session. Renders within a turn repeat the same usage, so a
+// usage equal to the last one is not counted again.
+func Record(path, session string, u Usage, now time.Time) (Session, error) {
+	return sessionstore.Update(path, session, now, func(s *Session, found bool) bool {
+		return s.record(u, found)
+	})
+}
+
+// record adds the turn's usage to the session, reporting whether it changed.
+func (s *Session) record(u Usage, found bool) bool {
+	if found && u == s.Last {
+		return false
+	}
+	s.Input += int64(u.Input)
+	s.CacheRead += int64(u.CacheRead)
+	s.CacheWrite += int64(u.CacheWrite)
+	if u.Miss() {
+		s.Misses++
+	} else {
+		s.Misses = 0
+	}
+	s.Last = u
+	return true
+}
+
+// HitPct returns the percentage of the session's input tokens read from the
+// cache, rounded down. It reports false before any input was seen.
+func (s Session) HitPct() (int, bool) {
+	total := s.Input + s.CacheRead + s.CacheWrite
+	if total == 0 {
+		return 0, false
+	}
+	return int(s.CacheRead * 100 / total), true
+}
@@ -0,0 +1,75 @@
+package cachehit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecord(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "cachehit.json")
+	cold := Usage{Input: 3, CacheWrite: 20_000}
+	warm := Usage{Input: 2, CacheRead: 20_000, CacheWrite: 500}
+	var got Session
+	for _, u := range []Usage{cold, cold, warm, warm} {
+		var err error
+		if got, err = Record(path, "a", u, now); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	want := Session{Input: 5, CacheRead: 20_000, CacheWrite: 20_500, Last: warm}
+	if got != want {
+		t.Errorf("Record() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSession_record(t *testing.T) {
+	t.Parallel()
+
+	var s Session
+	tests := []struct {
+		u    Usage
+		want int
+	}{
+		{u: Usage{Input: 3, CacheWrite: 1000}, want: 1},
+		{u: Usage{Input: 4, CacheWrite: 1200}, want: 2},
+		{u: Usage{Input: 4, CacheWrite: 1200}, want: 2}, // re-render of the same turn
+		{u: Usage{Input: 5, CacheWrite: 1400}, want: 3},
+		{u: Usage{Input: 1, CacheRead: 1400, CacheWrite: 100}, want: 0},
+		{u: Usage{Input: 2, CacheWrite: 1600}, want: 1},
+	}
+	for i, tt := range tests {
+		s.record(tt.u, i > 0)
+		if s.Misses != tt.want {
+			t.Errorf("turn %d: Misses = %d, want %d", i, s.Misses, tt.want)
+		}
+	}
+}
+
+func TestHitPct(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		s      Session
+		want   int
+		wantOK bool
+	}{
+		{name: "no input", s: Session{}},
+		{name: "all cached", s: Session{CacheRead: 1000}, want: 100, wantOK: true},
+		{name: "rounds down", s: Session{Input: 1, CacheRead: 998, CacheWrite: 1}, want: 99, wantOK: true},
+		{name: "cold", s: Session{Input: 3, CacheWrite: 997}, want: 0, wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, ok := tt.s.HitPct()
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("HitPct() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,421 @@
+// Package config loads claudeline settings from a JSON config file, with
+// CLAUDELINE_* environment variables and command-line flags layered on top.
+//
+// Precedence, lowest to highest: built-in defaults, config file, environment
+// variables, command-line flags.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/fredrikaverpil/claudeline/internal/paths"
+)
+
+// FileName is the config file name looked up in each config directory.
+const FileName = "claudeline.json"
+
+// EnvPrefix is prepended to the upper-cased flag name to form the
+// environment variable for a setting (e.g. -cwd-max-len → CLAUDELINE_CWD_MAX_LEN).
+const EnvPrefix = "CLAUDELINE_"
+
+// Config holds all user-facing settings.
+//
+// Every field with a flag tag is also settable as a command-line flag and as
+// a CLAUDELINE_* environment variable. The json tag is the config file key.
+type Config struct {
+	Debug           bool       `json:"debug"              flag:"debug"              usage:"write warnings and errors to the debug log"`
+	ShowGitBranch   bool       `json:"git_branch"         flag:"git-branch"         usage:"show git branch in the status line"`
+	GitStatus       bool       `json:"git_status"         flag:"git-status"         usage:"show working tree state next to the git branch (runs git status)"`
+	GitAheadBehind  bool       `json:"git_ahead_behind"   flag:"git-ahead-behind"   usage:"show commits ahead of and behind the upstream next to the git branch (runs git rev-list)"`
+	GitBranchMaxLen int        `json:"git_branch_max_len" flag:"git-branch-max-len" usage:"max display length for git branch"`
+	ShowCwd         bool       `json:"cwd"                flag:"cwd"                usage:"show working directory name in the status line"`
+	CwdMaxLen       int        `json:"cwd_max_len"        flag:"cwd-max-len"        usage:"max display length for working directory name"`
+	ModelMaxLen     int        `json:"model_max_len"      flag:"model-max-len"      usage:"max display length for model name (0: no limit)"`
+	ShowCost        bool       `json:"cost"               flag:"cost"               usage:"show estimated session cost in the status line (always on for API key users)"`
+	ShowCacheHit    bool       `json:"cache_hit"          flag:"cache-hit"          usage:"show the session's prompt cache hit ratio in the status line"`
+	ShowProjectCost bool       `json:"project_cost"       flag:"project-cost"       usage:"show cumulative cost of the project across sessions"`
+	BudgetDaily     float64    `json:"budget_daily"       flag:"budget-daily"       usage:"daily cost budget in USD (0: none)"`
+	BudgetWeekly    float64    `json:"budget_weekly"      flag:"budget-weekly"      usage:"weekly cost budget in USD, Monday to Sunday (0: none)"`
+	BudgetProject   float64    `json:"budget_project"     flag:"budget-project"     usage:"cumulative per-project cost budget in USD (0: none)"`
+	BudgetZones     []int      `json:"budget_zones"       flag:"budget-zones"       usage:"budget warning thresholds in percent of a budget: warn,over (default: 80,100)"`
+	Layout          []string   `json:"layout"             flag:"layout"             usage:"comma-separated segment order (default: built-in order)"`
+	Lines           [][]string `json:"lines"              flag:"lines"              usage:"multi-line layout: lines separated by ';', segments by ',' (overrides -layout)"`
+	Format          string     `json:"format"             flag:"format"             usage:"text/template format for the status line (overrides -layout and -lines)"`
+	MaxWidth        int        `json:"max_width"          flag:"max-width"          usage:"max display width per line; segments are shortened or dropped to fit (default: $COLUMNS)"`
+	Theme           string     `json:"theme"              flag:"theme"              usage:"color theme: default, light, solarized, okabe-ito, monochrome or a custom theme"`
+	ContextBar      string     `json:"context_bar"        flag:"context-bar"        usage:"context bar style: block, eighths, braille, dots or pill"`
+	ContextBarWidth int        `json:"context_bar_width"  flag:"context-bar-width"  usage:"context bar width in cells"`
+	ContextTokens   string     `json:"context_tokens"     flag:"context-tokens"     usage:"token counts beside the context bar: total (84k/200k) or detail (also in, out and cache tokens)"`
+	QuotaBar        string     `json:"quota_bar"          flag:"quota-bar"          usage:"quota bar style: block, eighths, braille, dots or pill"`
+	QuotaBarWidth   int        `json:"quota_bar_width"    flag:"quota-bar-width"    usage:"quota bar width in cells"`
+	ContextZones    []int      `json:"context_zones"      flag:"context-zones"      usage:"context bar color zone boundaries in percent: warn,hot (default: 40,60)"`
+	QuotaZones      []int      `json:"quota_zones"        flag:"quota-zones"        usage:"quota bar color zone boundaries in percent: warn,critical (default: 75,90)"`
+	ModelZones      []int      `json:"model_zones"        flag:"model-zones"        usage:"per-model quota bar color zone boundaries in percent: warn,critical (default: quota zones)"`
+	ASCII           bool       `json:"ascii"              flag:"ascii"              usage:"draw with ASCII only, without emoji or block characters"`
+	Color           string     `json:"color"              flag:"color"              usage:"color depth: auto, none, 16, 256 or truecolor (default: auto, from NO_COLOR, COLORTERM and TERM)"`
+	NoHistory       bool       `json:"no_history"         flag:"no-history"         usage:"don't record the local usage history and project cost ledger"`
+
+	// Priorities overrides the per-segment drop priority used when a line is
+	// too wide (lower drops first, 0 never drops). Config file only.
+	Priorities map[string]int `json:"priorities"`
+
+	// Themes defines custom color themes by name, each mapping theme roles
+	// to color specs. Config file only.
+	Themes map[string]map[string]string `json:"themes"`
+
+	// ForgeURLs maps git hosts to a forge (github, gitlab, bitbucket or
+	// gitea) or a branch URL template, for linking the branch to self-hosted
+	// forges. Config file only.
+	ForgeURLs map[string]string `json:"forge_urls"`
+
+	// Debug options.
+	UsageFile  string `json:"usage_file"  flag:"usage-file"  usage:"read usage data from file instead of API"`
+	StatusFile string `json:"status_file" flag:"status-file" usage:"read status data from file instead of API"`
+	UpdateFile string `json:"update_file" flag:"update-file" usage:"read update data from file instead of API"`
+}
+
+// Default returns the built-in configuration.
+func Default() Config {
+	return Config{
+		GitBranchMaxLen: 30,
+		CwdMaxLen:       30,
+		ContextBarWidth: 5,
+		QuotaBarWidth:   5,
+	}
+}
+
+// Find returns the path of the first existing config file, or "" when none
+// exists. The profile-specific location ($CLAUDE_CONFIG_DIR, or ~/.claude)
+// is checked before $XDG_CONFIG_HOME/claudeline (or ~/.config/claudeline).
+func Find(configDir string) string {
+	for _, p := range Candidates(configDir) {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// Candidates returns the config file locations in lookup order.
+func Candidates(configDir string) []string {
+	if configDir == "" {
+		configDir = paths.DefaultConfigDir()
+	}
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	if xdg == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			xdg = filepath.Join(home, ".config")
+		}
+	}
+	candidates := []string{filepath.Join(configDir, FileName)}
+	if xdg != "" {
+		candidates = append(candidates, filepath.Join(xdg, "claudeline", FileName))
+	}
+	return candidates
+}
+
+// Load reads the config file at path on top of the defaults.
+// An empty path or a missing file yields the defaults without error.
+// An unreadable or malformed file yields the defaults and an error, and
+// out-of-range settings are reset to their defaults with an error, so that a
+// broken config never breaks the status line.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return Default(), fmt.Errorf("read config: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return Default(), fmt.Errorf("parse config %s: %w", path, err)
+	}
+	if err := cfg.Repair(); err != nil {
+		return cfg, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Repair resets settings that would produce a broken status line to their
+// defaults, leaving the others alone, and reports what it reset.
+func (c *Config) Repair() error {
+	var errs []error
+	def := Default()
+	if c.GitBranchMaxLen < 1 {
+		errs = append(errs, fmt.Errorf("git_branch_max_len must be at least 1, got %d", c.GitBranchMaxLen))
+		c.GitBranchMaxLen = def.GitBranchMaxLen
+	}
+	if c.CwdMaxLen < 1 {
+		errs = append(errs, fmt.Errorf("cwd_max_len must be at least 1, got %d", c.CwdMaxLen))
+		c.CwdMaxLen = def.CwdMaxLen
+	}
+	if c.ContextBarWidth < 1 {
+		errs = append(errs, fmt.Errorf("context_bar_width must be at least 1, got %d", c.ContextBarWidth))
+		c.ContextBarWidth = def.ContextBarWidth
+	}
+	if c.QuotaBarWidth < 1 {
+		errs = append(errs, fmt.Errorf("quota_bar_width must be at least 1, got %d", c.QuotaBarWidth))
+		c.QuotaBarWidth = def.QuotaBarWidth
+	}
+	if c.ModelMaxLen < 0 {
+		errs = append(errs, fmt.Errorf("model_max_len must not be negative, got %d", c.ModelMaxLen))
+		c.ModelMaxLen = def.ModelMaxLen
+	}
+	if c.MaxWidth < 0 {
+		errs = append(errs, fmt.Errorf("max_width must not be negative, got %d", c.MaxWidth))
+		c.MaxWidth = def.MaxWidth
+	}
+	switch c.ContextTokens {
+	case "", "total", "detail":
+	default:
+		errs = append(errs, fmt.Errorf("context_tokens must be total or detail, got %q", c.ContextTokens))
+		c.ContextTokens = def.ContextTokens
+	}
+	for _, z := range []struct {
+		key    string
+		bounds *[]int
+	}{
+		{"context_zones", &c.ContextZones},
+		{"quota_zones", &c.QuotaZones},
+		{"model_zones", &c.ModelZones},
+		{"budget_zones", &c.BudgetZones},
+	} {
+		if err := validateZones(*z.bounds); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", z.key, err))
+			*z.bounds = nil
+		}
+	}
+	for _, b := range []struct {
+		key string
+		usd *float64
+	}{
+		{"budget_daily", &c.BudgetDaily},
+		{"budget_weekly", &c.BudgetWeekly},
+		{"budget_project", &c.BudgetProject},
+	} {
+		if *b.usd < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %g", b.key, *b.usd))
+			*b.usd = 0
+		}
+	}
+	badPriority := false
+	for _, name := range slices.Sorted(maps.Keys(c.Priorities)) {
+		if p := c.Priorities[name]; p < 0 {
+			errs = append(errs, fmt.Errorf("priorities.%s must not be negative, got %d", name, p))
+			badPriority = true
+		}
+	}
+	if badPriority {
+		// Drop the whole map, as for an unknown segment name, rather than
+		// edit a map the caller may share.
+		c.Priorities = nil
+	}
+	return errors.Join(errs...)
+}
+
+// validateZones checks that zone boundaries are two increasing percentages.
+// Nil means the built-in zones.
+func validateZones(bounds []int) error {
+	if bounds == nil {
+		return nil
+	}
+	if len(bounds) != 2 {
+		return fmt.Errorf("want 2 boundaries, got %d", len(bounds))
+	}
+	for _, b := range bounds {
+		if b < 0 || b > 100 {
+			return fmt.Errorf("boundary %d is outside 0–100", b)
+		}
+	}
+	if bounds[0] >= bounds[1] {
+		return fmt.Errorf("boundaries must increase, got %d then %d", bounds[0], bounds[1])
+	}
+	return nil
+}
+
+// UsesSegment reports whether the layout or any of the lines explicitly
+// lists the named segment.
+func (c Config) UsesSegment(name string) bool {
+	if slices.Contains(c.Layout, name) {
+		return true
+	}
+	for _, line := range c.Lines {
+		if slices.Contains(line, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Bind registers a flag on fs for every flag-tagged field of c, using the
+// current field values as defaults.
+func Bind(fs *flag.FlagSet, c *Config) {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name := field.Tag.Get("flag")
+		if name == "" {
+			continue
+		}
+		usage := field.Tag.Get("usage")
+		switch p := v.Field(i).Addr().Interface().(type) {
+		case *bool:
+			fs.BoolVar(p, name, *p, usage)
+		case *int:
+			fs.IntVar(p, name, *p, usage)
+		case *float64:
+			fs.Float64Var(p, name, *p, usage)
+		case *string:
+			fs.StringVar(p, name, *p, usage)
+		case *[]string:
+			fs.Var((*listValue)(p), name, usage)
+		case *[][]string:
+			fs.Var((*linesValue)(p), name, usage)
+		case *[]int:
+			fs.Var((*intListValue)(p), name, usage)
+		default:
+			panic(fmt.Sprintf("config: unsupported flag type %s for %s", field.Type, field.Name))
+		}
+	}
+}
+
+// EnvName returns the environment variable name for a flag name.
+func EnvName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// ApplyEnv overrides settings from CLAUDELINE_* environment variables.
+// Invalid values are skipped and reported in the returned error.
+func (c *Config) ApplyEnv(getenv func(string) string) error {
+	fs := flag.NewFlagSet("env", flag.ContinueOnError)
+	Bind(fs, c)
+	var errs []error
+	fs.VisitAll(func(f *flag.Flag) {
+		env := EnvName(f.Name)
+		val := getenv(env)
+		if val == "" {
+			return
+		}
+		if err := fs.Set(f.Name, val); err != nil {
+			errs = append(errs, fmt.Errorf("%s=%q: %w", env, val, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// ApplyFlags overrides settings with the flags that were explicitly set on
+// from. Flags not bound to a Config field (e.g. -version) are ignored.
+func (c *Config) ApplyFlags(from *flag.FlagSet) error {
+	fs := flag.NewFlagSet("flags", flag.ContinueOnError)
+	Bind(fs, c)
+	var errs []error
+	from.Visit(func(f *flag.Flag) {
+		if fs.Lookup(f.Name) == nil {
+			return
+		}
+		if err := fs.Set(f.Name, f.Value.String()); err != nil {
+			errs = append(errs, fmt.Errorf("-%s: %w", f.Name, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// listValue is a comma-separated []string flag value.
+type listValue []string
+
+func (l *listValue) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(s string) error {
+	var items []string
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*l = items
+	return nil
+}
+
+// intListValue is a comma-separated []int flag value.
+type intListValue []int
+
+func (l *intListValue) String() string {
+	if l == nil {
+		return ""
+	}
+	items := make([]string, len(*l))
+	for i, n := range *l {
+		items[i] = strconv.Itoa(n)
+	}
+	return strings.Join(items, ",")
+}
+
+func (l *intListValue) Set(s string) error {
+	var items listValue
+	if err := items.Set(s); err != nil {
+		return err
+	}
+	ints := make([]int, 0, len(items))
+	for _, item := range items {
+		n, err := strconv.Atoi(item)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", item)
+		}
+		ints = append(ints, n)
+	}
+	*l = ints
+	return nil
+}
+
+// linesValue is a multi-line layout flag value: lines separated by ';',
+// items within a line separated by ','.
+type linesValue [][]string
+
+func (l *linesValue) String() string {
+	if l == nil {
+		return ""
+	}
+	lines := make([]string, len(*l))
+	for i, line := range *l {
+		lines[i] = strings.Join(line, ",")
+	}
+	return strings.Join(lines, ";")
+}
+
+func (l *linesValue) Set(s string) error {
+	var lines [][]string
+	for line := range strings.SplitSeq(s, ";") {
+		var items listValue
+		if err := items.Set(line); err != nil {
+			return err
+		}
+		if len(items) > 0 {
+			lines = append(lines, items)
+		}
+	}
+	*l = lines
+	return nil
+}
@@ -0,0 +1,306 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    Config
+		wantErr bool
+	}{
+		{
+			name: "empty path",
+			path: "",
+			want: Default(),
+		},
+		{
+			name: "missing file",
+			path: filepath.Join(dir, "missing.json"),
+			want: Default(),
+		},
+		{
+			name: "partial file keeps defaults",
+			path: write("partial.json", `{"cwd": true, "layout": ["context", "identity"]}`),
+			want: Config{
+				ShowCwd:         true,
+				CwdMaxLen:       30,
+				GitBranchMaxLen: 30,
+				ContextBarWidth: 5,
+				QuotaBarWidth:   5,
+				Layout:          []string{"context", "identity"},
+			},
+		},
+		{
+			name: "custom theme",
+			path: write("theme.json", `{"theme": "mine", "themes": {"mine": {"base": "light", "branch": "#ff00ff"}}}`),
+			want: Config{
+				CwdMaxLen:       30,
+				GitBranchMaxLen: 30,
+				ContextBarWidth: 5,
+				QuotaBarWidth:   5,
+				Theme:           "mine",
+				Themes:          map[string]map[string]string{"mine": {"base": "light", "branch": "#ff00ff"}},
+			},
+		},
+		{
+			name: "zones",
+			path: write("zones.json", `{"context_zones": [50, 70], "model_zones": [60, 95]}`),
+			want: Config{
+				CwdMaxLen:       30,
+				GitBranchMaxLen: 30,
+				ContextBarWidth: 5,
+				QuotaBarWidth:   5,
+				ContextZones:    []int{50, 70},
+				ModelZones:      []int{60, 95},
+			},
+		},
+		{
+			name: "budgets",
+			path: write("budgets.json", `{"budget_daily": 12.5, "budget_project": 200, "budget_zones": [50, 100]}`),
+			want: Config{
+				CwdMaxLen:       30,
+				GitBranchMaxLen: 30,
+				ContextBarWidth: 5,
+				QuotaBarWidth:   5,
+				BudgetDaily:     12.5,
+				BudgetProject:   200,
+				BudgetZones:     []int{50, 100},
+			},
+		},
+		{
+			name:    "negative budget is reset",
+			path:    write("budget-negative.json", `{"budget_weekly": -1}`),
+			want:    Default(),
+			wantErr: true,
+		},
+		{
+			name:    "unknown context tokens mode is reset",
+			path:    write("tokens.json", `{"context_tokens": "all"}`),
+			want:    Default(),
+			wantErr: true,
+		},
+		{
+			name:    "non-monotonic zones are reset",
+			path:    write("zones-order.json", `{"quota_zones": [90, 75]}`),
+			want:    Default(),
+			wantErr: true,
+		},
+		{
+			name:    "zone outside 0-100 is reset",
+			path:    write("zones-range.json", `{"context_zones": [40, 160]}`),
+			want:    Default(),
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON falls back to defaults",
+			path:    write("invalid.json", `{"cwd": tru`),
+			want:    Default(),
+			wantErr: true,
+		},
+		{
+			name:    "unknown key falls back to defaults",
+			path:    write("unknown.json", `{"cwd": true, "cwd_maxlen": 10}`),
+			want:    Default(),
+			wantErr: true,
+		},
+		{
+			name: "invalid value is reset and the rest is kept",
+			path: write("zero.json", `{"cwd": true, "cwd_max_len": 0}`),
+			want: Config{
+				ShowCwd:         true,
+				CwdMaxLen:       30,
+				GitBranchMaxLen: 30,
+				ContextBarWidth: 5,
+				QuotaBarWidth:   5,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Load(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Load() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte(`{"cwd": true, "cwd_max_len": 10, "git_branch_max_len": 20}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Arrange.
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	scratch := Default()
+	Bind(fs, &scratch)
+	if err := fs.Parse([]string{"-cwd-max-len", "40", "-layout", "context, 5h"}); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"CLAUDELINE_CWD_MAX_LEN":        "15",
+		"CLAUDELINE_GIT_BRANCH_MAX_LEN": "25",
+		"CLAUDELINE_COST":               "true",
+	}
+
+	// Act.
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.ApplyEnv(func(k string) string { return env[k] }); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.ApplyFlags(fs); err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert.
+	want := Config{
+		ShowCwd:         true,                      // file
+		CwdMaxLen:       40,                        // flag beats env and file
+		GitBranchMaxLen: 25,                        // env beats file
+		ShowCost:        true,                      // env
+		Layout:          []string{"context", "5h"}, // flag
+		ContextBarWidth: 5,                         // default
+		QuotaBarWidth:   5,                         // default
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("config = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestLinesFlag(t *testing.T) {
+	t.Parallel()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := Default()
+	Bind(fs, &cfg)
+	if err := fs.Parse([]string{"-lines", "identity, cwd;; context,5h ;"}); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"identity", "cwd"}, {"context", "5h"}}
+	if !reflect.DeepEqual(cfg.Lines, want) {
+		t.Errorf("Lines = %q, want %q", cfg.Lines, want)
+	}
+	if !cfg.UsesSegment("cwd") || cfg.UsesSegment("branch") {
+		t.Errorf("UsesSegment() mismatch for lines %q", cfg.Lines)
+	}
+}
+
+func TestZonesFlag(t *testing.T) {
+	t.Parallel()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := Default()
+	Bind(fs, &cfg)
+	if err := fs.Parse([]string{"-quota-zones", "60, 80"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{60, 80}; !reflect.DeepEqual(cfg.QuotaZones, want) {
+		t.Errorf("QuotaZones = %v, want %v", cfg.QuotaZones, want)
+	}
+	if err := fs.Set("context-zones", "40,high"); err == nil {
+		t.Error("Set(context-zones) error = nil, want error for non-number")
+	}
+}
+
+func TestBudgetFlag(t *testing.T) {
+	t.Parallel()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := Default()
+	Bind(fs, &cfg)
+	if err := fs.Parse([]string{"-budget-weekly", "49.99"}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BudgetWeekly != 49.99 {
+		t.Errorf("BudgetWeekly = %v, want 49.99", cfg.BudgetWeekly)
+	}
+}
+
+func TestApplyEnv_invalid(t *testing.T) {
+	t.Parallel()
+
+	cfg := Default()
+	env := map[string]string{
+		"CLAUDELINE_CWD":         "maybe",
+		"CLAUDELINE_CWD_MAX_LEN": "12",
+	}
+	err := cfg.ApplyEnv(func(k string) string { return env[k] })
+	if err == nil {
+		t.Fatal("ApplyEnv() error = nil, want error for invalid bool")
+	}
+	if cfg.ShowCwd {
+		t.Error("ShowCwd = true, want invalid value skipped")
+	}
+	if cfg.CwdMaxLen != 12 {
+		t.Errorf("CwdMaxLen = %d, want 12", cfg.CwdMaxLen)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	t.Parallel()
+
+	if got := EnvName("git-branch-max-len"); got != "CLAUDELINE_GIT_BRANCH_MAX_LEN" {
+		t.Errorf("EnvName() = %q, want %q", got, "CLAUDELINE_GIT_BRANCH_MAX_LEN")
+	}
+}
+
+func TestFind(t *testing.T) {
+	configDir := t.TempDir()
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+
+	if got := Find(configDir); got != "" {
+		t.Errorf("Find() = %q, want empty when no file exists", got)
+	}
+
+	xdgFile := filepath.Join(xdg, "claudeline", FileName)
+	if err := os.MkdirAll(filepath.Dir(xdgFile), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(xdgFile, []byte(`{}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := Find(configDir); got != xdgFile {
+		t.Errorf("Find() = %q, want %q", got, xdgFile)
+	}
+
+	profileFile := filepath.Join(configDir, FileName)
+	if err := os.WriteFile(profileFile, []byte(`{}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := Find(configDir); got != profileFile {
+		t.Errorf("Find() = %q, want profile file %q to take precedence", got, profileFile)
+	}
+}
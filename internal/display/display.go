@@ -0,0 +1,324 @@
+// Package display measures and truncates strings in terminal cells.
+package display
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Width returns the number of terminal cells s occupies. ANSI escape
+// sequences (SGR colors, OSC 8 hyperlinks) take no space. Width is measured
+// per grapheme cluster: wide East Asian characters and emoji (including ZWJ
+// sequences, flags and symbols with VS16) take two cells, and combining
+// marks take none.
+func Width(s string) int {
+	w := 0
+	for len(s) > 0 {
+		if s[0] == '\033' {
+			s = s[escapeLen(s):]
+			continue
+		}
+		end := strings.IndexByte(s, '\033')
+		if end < 0 {
+			end = len(s)
+		}
+		for _, g := range Graphemes(s[:end]) {
+			w += clusterWidth(g)
+		}
+		s = s[end:]
+	}
+	return w
+}
+
+// TruncateMiddle shortens s to at most maxWidth cells by replacing its middle
+// with ellipsis (e.g. "…"). It never splits a grapheme cluster, so a wide
+// character that doesn't fit is left out entirely. s must not contain escape
+// sequences. A maxWidth of 0 or less disables truncation.
+func TruncateMiddle(s string, maxWidth int, ellipsis string) string {
+	if maxWidth <= 0 || Width(s) <= maxWidth {
+		return s
+	}
+	budget := maxWidth - Width(ellipsis)
+	if budget <= 0 {
+		// Not even the ellipsis fits; show as much of it as does.
+		return truncateEnd(ellipsis, maxWidth)
+	}
+	clusters := Graphemes(s)
+
+	var head strings.Builder
+	used, i := 0, 0
+	for ; i < len(clusters); i++ {
+		w := clusterWidth(clusters[i])
+		if used+w > budget/2 {
+			break
+		}
+		head.WriteString(clusters[i])
+		used += w
+	}
+
+	// The tail gets the rest of the budget, including what the head could
+	// not use because a wide cluster didn't fit.
+	tailBudget := budget - used
+	j := len(clusters)
+	for tailUsed := 0; j > i; j-- {
+		w := clusterWidth(clusters[j-1])
+		if tailUsed+w > tailBudget {
+			break
+		}
+		tailUsed += w
+	}
+	return head.String() + ellipsis + strings.Join(clusters[j:], "")
+}
+
+// truncateEnd returns the leading grapheme clusters of s that fit in
+// maxWidth cells.
+func truncateEnd(s string, maxWidth int) string {
+	var sb strings.Builder
+	w := 0
+	for _, g := range Graphemes(s) {
+		w += clusterWidth(g)
+		if w > maxWidth {
+			break
+		}
+		sb.WriteString(g)
+	}
+	return sb.String()
+}
+
+// Graphemes splits s into user-perceived characters (extended grapheme
+// clusters). It implements the subset of Unicode UAX #29 that matters for
+// names and status line glyphs: combining marks, variation selectors,
+// emoji modifiers and ZWJ sequences, regional indicator pairs (flags),
+// Hangul jamo and CR LF.
+func Graphemes(s string) []string {
+	var clusters []string
+	start := 0
+	var prev rune = -1
+	riCount := 0 // consecutive regional indicators in the current cluster
+	for i, r := range s {
+		if prev >= 0 && !breakBetween(prev, r, riCount) {
+			if isRegionalIndicator(r) {
+				riCount++
+			}
+			prev = r
+			continue
+		}
+		if i > start {
+			clusters = append(clusters, s[start:i])
+		}
+		start = i
+		prev = r
+		riCount = 0
+		if isRegionalIndicator(r) {
+			riCount = 1
+		}
+	}
+	if start < len(s) {
+		clusters = append(clusters, s[start:])
+	}
+	return clusters
+}
+
+// breakBetween reports whether there is a grapheme cluster boundary between
+// prev and r.
+func breakBetween(prev, r rune, riCount int) bool {
+	switch {
+	case prev == '\r' && r == '\n':
+		return false
+	case isControl(prev) || isControl(r):
+		return true
+	case extends(r):
+		return false
+	case prev == 0x200D && isPictographic(r):
+		return false // emoji ZWJ sequence
+	case isRegionalIndicator(prev) && isRegionalIndicator(r):
+		return riCount%2 == 0 // pair up flags
+	case isHangulL(prev) && (isHangulL(r) || isHangulV(r) || isHangulSyllable(r)):
+		return false
+	}
+	return true
+}
+
+// clusterWidth returns the cell width of a single grapheme cluster.
+func clusterWidth(g string) int {
+	r, _ := utf8.DecodeRuneInString(g)
+	switch {
+	case isControl(r):
+		return 0
+	case wide(r), isRegionalIndicator(r):
+		return 2
+	case strings.ContainsRune(g, 0xFE0F) && isPictographic(r):
+		// VS16 requests emoji presentation, widening a narrow symbol such
+		// as ⚠ (U+26A0) to two cells.
+		return 2
+	case zeroWidth(r):
+		return 0
+	default:
+		return 1
+	}
+}
+
+// escapeLen returns the byte length of the escape sequence at the start of s.
+func escapeLen(s string) int {
+	if len(s) < 2 {
+		return len(s)
+	}
+	switch s[1] {
+	case '[':
+		// CSI: parameters and intermediates, then a final byte in 0x40–0x7E.
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7E {
+				return i + 1
+			}
+		}
+		return len(s)
+	case ']':
+		// OSC (e.g. OSC 8 hyperlinks): terminated by BEL or ST (ESC \).
+		for i := 2; i < len(s); i++ {
+			switch {
+			case s[i] == '\a':
+				return i + 1
+			case s[i] == '\033' && i+1 < len(s) && s[i+1] == '\\':
+				return i + 2
+			}
+		}
+		return len(s)
+	default:
+		return 2
+	}
+}
+
+// zeroWidth reports whether r occupies no cell of its own.
+func zeroWidth(r rune) bool {
+	return extends(r) || unicode.In(r, unicode.Cf)
+}
+
+// extends reports whether r continues the preceding grapheme cluster.
+func extends(r rune) bool {
+	switch {
+	case r == 0x200D: // zero width joiner
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF: // emoji skin tone modifiers
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // emoji tag sequences
+		return true
+	case isHangulV(r) || isHangulT(r):
+		return true
+	}
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc, unicode.Variation_Selector)
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 || (r >= 0x7F && r < 0xA0) || r == 0x2028 || r == 0x2029
+}
+
+func isRegionalIndicator(r rune) bool { return r >= 0x1F1E6 && r <= 0x1F1FF }
+
+// isPictographic approximates the Extended_Pictographic property.
+func isPictographic(r rune) bool {
+	switch {
+	case r == 0x00A9 || r == 0x00AE || r == 0x203C || r == 0x2049 || r == 0x2122 || r == 0x2139:
+		return true
+	case r >= 0x2190 && r <= 0x21FF, r >= 0x2300 && r <= 0x23FF:
+		return true
+	case r >= 0x2460 && r <= 0x27BF, r >= 0x2900 && r <= 0x2BFF:
+		return true
+	case r >= 0x1F000 && r <= 0x1FAFF && !isRegionalIndicator(r):
+		return true
+	}
+	return false
+}
+
+// Hangul jamo classes: leading consonants, vowels, trailing consonants.
+func isHangulL(r rune) bool        { return r >= 0x1100 && r <= 0x115F || r >= 0xA960 && r <= 0xA97F }
+func isHangulV(r rune) bool        { return r >= 0x1160 && r <= 0x11A7 || r >= 0xD7B0 && r <= 0xD7C6 }
+func isHangulT(r rune) bool        { return r >= 0x11A8 && r <= 0x11FF || r >= 0xD7CB && r <= 0xD7FB }
+func isHangulSyllable(r rune) bool { return r >= 0xAC00 && r <= 0xD7A3 }
+
+// wideRanges lists the East Asian Wide/Fullwidth and default emoji
+// presentation code point ranges, sorted by start.
+var wideRanges = [][2]rune{
+	{0x1100, 0x115F},
+	{0x231A, 0x231B},
+	{0x2329, 0x232A},
+	{0x23E9, 0x23EC},
+	{0x23F0, 0x23F0},
+	{0x23F3, 0x23F3},
+	{0x25FD, 0x25FE},
+	{0x2614, 0x2615},
+	{0x2648, 0x2653},
+	{0x267F, 0x267F},
+	{0x2693, 0x2693},
+	{0x26A1, 0x26A1},
+	{0x26AA, 0x26AB},
+	{0x26BD, 0x26BE},
+	{0x26C4, 0x26C5},
+	{0x26CE, 0x26CE},
+	{0x26D4, 0x26D4},
+	{0x26EA, 0x26EA},
+	{0x26F2, 0x26F3},
+	{0x26F5, 0x26F5},
+	{0x26FA, 0x26FA},
+	{0x26FD, 0x26FD},
+	{0x2705, 0x2705},
+	{0x270A, 0x270B},
+	{0x2728, 0x2728},
+	{0x274C, 0x274C},
+	{0x274E, 0x274E},
+	{0x2753, 0x2755},
+	{0x2757, 0x2757},
+	{0x2795, 0x2797},
+	{0x27B0, 0x27B0},
+	{0x27BF, 0x27BF},
+	{0x2B1B, 0x2B1C},
+	{0x2B50, 0x2B50},
+	{0x2B55, 0x2B55},
+	{0x2E80, 0x303E},
+	{0x3041, 0x33FF},
+	{0x3400, 0x4DBF},
+	{0x4E00, 0x9FFF},
+	{0xA000, 0xA4CF},
+	{0xA960, 0xA97F},
+	{0xAC00, 0xD7A3},
+	{0xF900, 0xFAFF},
+	{0xFE10, 0xFE19},
+	{0xFE30, 0xFE6F},
+	{0xFF00, 0xFF60},
+	{0xFFE0, 0xFFE6},
+	{0x16FE0, 0x16FE4},
+	{0x17000, 0x18CFF},
+	{0x1B000, 0x1B2FF},
+	{0x1F004, 0x1F004},
+	{0x1F0CF, 0x1F0CF},
+	{0x1F18E, 0x1F18E},
+	{0x1F191, 0x1F19A},
+	{0x1F200, 0x1F2FF},
+	{0x1F300, 0x1F64F},
+	{0x1F680, 0x1F6FF},
+	{0x1F7E0, 0x1F7FF},
+	{0x1F900, 0x1F9FF},
+	{0x1FA70, 0x1FAFF},
+	{0x20000, 0x2FFFD},
+	{0x30000, 0x3FFFD},
+}
+
+// wide reports whether r occupies two cells.
+func wide(r rune) bool {
+	if r < wideRanges[0][0] {
+		return false
+	}
+	lo, hi := 0, len(wideRanges)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		switch {
+		case r < wideRanges[mid][0]:
+			hi = mid
+		case r > wideRanges[mid][1]:
+			lo = mid + 1
+		default:
+			return true
+		}
+	}
+	return false
+}
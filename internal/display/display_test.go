@@ -0,0 +1,114 @@
+package display
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWidth(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		s    string
+		want int
+	}{
+		{name: "empty", s: "", want: 0},
+		{name: "ascii", s: "main", want: 4},
+		{name: "box drawing and blocks", s: "██░ │", want: 5},
+		{name: "nbsp", s: "a\u00A0b", want: 3},
+		{name: "sgr colors", s: "\033[32mok\033[0m", want: 2},
+		{name: "256 color", s: "\033[38;5;208m🔥▂\033[0m", want: 3},
+		{name: "osc 8 hyperlink", s: "\033]8;;https://example.com\a↑\033]8;;\a", want: 1},
+		{name: "osc 8 with ST", s: "\033]8;;https://example.com\033\\link\033]8;;\033\\", want: 4},
+		{name: "warning with VS16", s: "⚠️", want: 2},
+		{name: "warning without VS16", s: "⚠", want: 1},
+		{name: "wide emoji", s: "🥵🥊", want: 4},
+		{name: "high voltage with VS16", s: "⚡️", want: 2},
+		{name: "cjk", s: "日本語", want: 6},
+		{name: "hangul", s: "한국", want: 4},
+		{name: "combining accent", s: "e\u0301", want: 1},
+		{name: "zwj family", s: "👨\u200d👩\u200d👧", want: 2},
+		{name: "skin tone", s: "👍🏽", want: 2},
+		{name: "flag", s: "🇸🇪", want: 2},
+		{name: "decomposed hangul", s: "\u1112\u1161\u11AB", want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := Width(tt.s); got != tt.want {
+				t.Errorf("Width(%q) = %d, want %d", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphemes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		s    string
+		want []string
+	}{
+		{name: "empty", s: "", want: nil},
+		{name: "ascii", s: "ab", want: []string{"a", "b"}},
+		{name: "combining accent", s: "e\u0301x", want: []string{"e\u0301", "x"}},
+		{name: "zwj family", s: "👨\u200d👩\u200d👧!", want: []string{"👨\u200d👩\u200d👧", "!"}},
+		{name: "flags pair up", s: "🇸🇪🇳🇴", want: []string{"🇸🇪", "🇳🇴"}},
+		{name: "vs16", s: "⚠\uFE0F ", want: []string{"⚠\uFE0F", " "}},
+		{name: "crlf", s: "a\r\nb", want: []string{"a", "\r\n", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := Graphemes(tt.s); !slices.Equal(got, tt.want) {
+				t.Errorf("Graphemes(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateMiddle(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		s        string
+		maxWidth int
+		ellipsis string // default "…"
+		want     string
+	}{
+		{name: "fits", s: "main", maxWidth: 4, want: "main"},
+		{name: "disabled", s: "main", maxWidth: 0, want: "main"},
+		{name: "ascii", s: "abcdefgh", maxWidth: 5, want: "ab…gh"},
+		{name: "only ellipsis", s: "abc", maxWidth: 1, want: "…"},
+		{name: "cjk", s: "日本語テスト", maxWidth: 7, want: "日…スト"},
+		{name: "wide char left out", s: "日本語テスト", maxWidth: 6, want: "日…ト"},
+		{name: "flag kept whole", s: "🇸🇪-stockholm", maxWidth: 6, want: "🇸🇪…olm"},
+		{name: "ascii ellipsis", s: "abcdefgh", maxWidth: 6, ellipsis: "...", want: "a...gh"},
+		{name: "ascii ellipsis clipped", s: "abcdefgh", maxWidth: 2, ellipsis: "...", want: ".."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ellipsis := tt.ellipsis
+			if ellipsis == "" {
+				ellipsis = "…"
+			}
+			got := TruncateMiddle(tt.s, tt.maxWidth, ellipsis)
+			if got != tt.want {
+				t.Errorf("TruncateMiddle(%q, %d) = %q, want %q", tt.s, tt.maxWidth, got, tt.want)
+			}
+			if tt.maxWidth > 0 && Width(got) > tt.maxWidth {
+				t.Errorf("TruncateMiddle(%q, %d) width = %d, exceeds maxWidth", tt.s, tt.maxWidth, Width(got))
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+// Package filelock serializes read-modify-write updates of files shared by
+// concurrent renders, with a lock file next to the shared file.
+package filelock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// timeout is how long Lock waits for another render's update.
+	timeout = 200 * time.Millisecond
+	// stale is the age at which a lock file is considered abandoned.
+	stale = 5 * time.Second
+)
+
+// ErrLocked is returned by Lock when another update holds the lock.
+var ErrLocked = errors.New("file is locked")
+
+// Lock takes the lock file next to path, returning the function that
+// releases it. A lock left behind by a crashed render is taken over once it
+// is stale.
+func Lock(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > stale {
+			_ = os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, ErrLocked
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,56 @@
+// Package fillrate tracks how fast each session's context window fills per
+// turn, to estimate the turns left before auto-compaction.
+package fillrate
+
+import (
+	"math"
+	"time"
+
+	"github.com/fredrikaverpil/claudeline/internal/sessionstore"
+)
+
+// minTurns is the number of turns averaged before estimating.
+const minTurns = 2
+
+// Session is a session's context growth since tracking started or the
+// context last shrank (compaction or /clear).
+type Session struct {
+	Start float64 `json:"start"` // used percentage at the start
+	Last  float64 `json:"last"`  // latest used percentage
+	Turns int     `json:"turns"` // renders where the percentage grew
+}
+
+// Record updates the session's growth in the state file at path with the
+// current used percentage and returns it. Each growth counts as a turn;
+// renders within a turn repeat the same percentage. A drop starts over.
+func Record(path, session string, pct float64, now time.Time) (Session, error) {
+	return sessionstore.Update(path, session, now, func(s *Session, found bool) bool {
+		return s.record(pct, found)
+	})
+}
+
+// record adds the used percentage to the session, reporting whether it
+// changed.
+func (s *Session) record(pct float64, found bool) bool {
+	switch {
+	case !found || pct < s.Last:
+		*s = Session{Start: pct, Last: pct}
+	case pct > s.Last:
+		s.Last = pct
+		s.Turns++
+	default:
+		return false
+	}
+	return true
+}
+
+// TurnsUntil estimates how many more turns fit before the used percentage
+// reaches threshold, at the session's average growth per turn. It reports
+// false until a few turns have been seen.
+func (s Session) TurnsUntil(threshold float64) (int, bool) {
+	if s.Turns < minTurns || s.Last <= s.Start {
+		return 0, false
+	}
+	perTurn := (s.Last - s.Start) / float64(s.Turns)
+	return max(0, int(math.Floor((threshold-s.Last)/perTurn))), true
+}
@@ -0,0 +1,61 @@
+package fillrate
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecord(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "fillrate.json")
+	record := func(pct float64) Session {
+		t.Helper()
+		s, err := Record(path, "a", pct, now)
+		if err != nil {
+			t.Fatalf("Record(%v) error = %v", pct, err)
+		}
+		return s
+	}
+	var got Session
+	for _, pct := range []float64{10, 10, 14, 14, 14, 20, 26} {
+		got = record(pct)
+	}
+	if want := (Session{Start: 10, Last: 26, Turns: 3}); got != want {
+		t.Errorf("Record() = %+v, want %+v", got, want)
+	}
+
+	// Compaction drops the percentage and starts over.
+	if got, want := record(5), (Session{Start: 5, Last: 5}); got != want {
+		t.Errorf("Record() after compaction = %+v, want %+v", got, want)
+	}
+}
+
+func TestTurnsUntil(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		s         Session
+		threshold float64
+		want      int
+		wantOK    bool
+	}{
+		{name: "too few turns", s: Session{Start: 10, Last: 20, Turns: 1}, threshold: 95},
+		// 5% per turn, 30% left.
+		{name: "estimate", s: Session{Start: 50, Last: 65, Turns: 3}, threshold: 95, want: 6, wantOK: true},
+		{name: "partial turn rounds down", s: Session{Start: 50, Last: 65, Turns: 3}, threshold: 94, want: 5, wantOK: true},
+		{name: "past threshold", s: Session{Start: 80, Last: 97, Turns: 4}, threshold: 95, want: 0, wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, ok := tt.s.TurnsUntil(tt.threshold)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("TurnsUntil(%v) = %d, %v, want %d, %v", tt.threshold, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
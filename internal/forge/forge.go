@@ -0,0 +1,138 @@
+// Package forge builds web URLs on code forges, such as GitHub, for git
+// remotes.
+package forge
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Templates are the built-in branch URL templates by forge. In a template,
+// {host} is the remote's host, {repo} its repository path (e.g.
+// "owner/repo") and {branch} the escaped branch name.
+var Templates = map[string]string{
+	"github":    "https://{host}/{repo}/tree/{branch}",
+	"gitlab":    "https://{host}/{repo}/-/tree/{branch}",
+	"bitbucket": "https://{host}/{repo}/branch/{branch}",
+	"gitea":     "https://{host}/{repo}/src/branch/{branch}",
+}
+
+// hosts maps well-known hosts to their forge.
+var hosts = map[string]string{
+	"github.com":    "github",
+	"gitlab.com":    "gitlab",
+	"bitbucket.org": "bitbucket",
+	"codeberg.org":  "gitea",
+	"gitea.com":     "gitea",
+}
+
+// Remote is a git remote on a web host.
+type Remote struct {
+	Host string // host, with the port for HTTP(S) remotes
+	Repo string // repository path without ".git", e.g. "owner/repo"
+}
+
+// ParseRemote parses a remote URL in the SSH ("git@host:owner/repo.git",
+// "ssh://git@host:22/owner/repo.git") or HTTP(S) form. Reports false for
+// local paths and other URLs without a host and path.
+func ParseRemote(remote string) (Remote, bool) {
+	var host, path string
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return Remote{}, false
+		}
+		switch u.Scheme {
+		case "http", "https":
+			host = u.Host // the web server keeps its port
+		case "ssh", "git", "git+ssh", "ssh+git":
+			host = u.Hostname()
+		default:
+			return Remote{}, false
+		}
+		path = u.Path
+	} else {
+		// scp-like syntax: [user@]host:path, with no slash before the colon.
+		before, after, ok := strings.Cut(remote, ":")
+		if !ok || strings.Contains(before, "/") {
+			return Remote{}, false
+		}
+		_, host, _ = strings.Cut(before, "@")
+		if host == "" {
+			host = before
+		}
+		path = after
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || path == "" {
+		return Remote{}, false
+	}
+	return Remote{Host: strings.ToLower(host), Repo: path}, true
+}
+
+// template returns the branch URL template for host: a custom entry for the
+// host, which names a forge or is a template itself, then the well-known
+// hosts, then a guess from the host name for self-hosted forges.
+func template(host string, custom map[string]string) string {
+	if t, ok := custom[host]; ok {
+		if builtin, ok := Templates[t]; ok {
+			return builtin
+		}
+		return t
+	}
+	if name, ok := hosts[host]; ok {
+		return Templates[name]
+	}
+	for _, guess := range []struct{ part, forge string }{
+		{"github", "github"},
+		{"gitlab", "gitlab"},
+		{"bitbucket", "bitbucket"},
+		{"gitea", "gitea"},
+		{"forgejo", "gitea"},
+	} {
+		if strings.Contains(host, guess.part) {
+			return Templates[guess.forge]
+		}
+	}
+	return ""
+}
+
+// BranchURL returns the web URL of branch on the forge hosting remote, or ""
+// when the remote or its forge is unknown. custom maps hosts to a forge name
+// from Templates or to a URL template, for self-hosted forges.
+func BranchURL(remote, branch string, custom map[string]string) string {
+	r, ok := ParseRemote(remote)
+	if !ok || branch == "" {
+		return ""
+	}
+	t := template(r.Host, custom)
+	if t == "" {
+		return ""
+	}
+	segments := strings.Split(branch, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.NewReplacer(
+		"{host}", r.Host,
+		"{repo}", r.Repo,
+		"{branch}", strings.Join(segments, "/"),
+	).Replace(t)
+}
+
+// Validate reports custom entries that are neither a forge name nor a
+// template with a {branch} placeholder.
+func Validate(custom map[string]string) error {
+	var errs []error
+	for _, host := range slices.Sorted(maps.Keys(custom)) {
+		t := custom[host]
+		if _, ok := Templates[t]; !ok && !strings.Contains(t, "{branch}") {
+			errs = append(errs, fmt.Errorf("forge_urls.%s: want github, gitlab, bitbucket, gitea or a template with {branch}, got %q", host, t))
+		}
+	}
+	return errors.Join(errs...)
+}
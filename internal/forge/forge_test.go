@@ -0,0 +1,142 @@
+package forge
+
+import "testing"
+
+func TestParseRemote(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		remote string
+		want   Remote
+		wantOK bool
+	}{
+		{remote: "git@github.com:owner/repo.git", want: Remote{Host: "github.com", Repo: "owner/repo"}, wantOK: true},
+		{remote: "github.com:owner/repo", want: Remote{Host: "github.com", Repo: "owner/repo"}, wantOK: true},
+		{remote: "ssh://git@GitLab.com:22/group/sub/repo.git", want: Remote{Host: "gitlab.com", Repo: "group/sub/repo"}, wantOK: true},
+		{remote: "git+ssh://git@bitbucket.org/team/repo", want: Remote{Host: "bitbucket.org", Repo: "team/repo"}, wantOK: true},
+		{remote: "https://github.com/owner/repo.git", want: Remote{Host: "github.com", Repo: "owner/repo"}, wantOK: true},
+		{remote: "https://user@codeberg.org/owner/repo/", want: Remote{Host: "codeberg.org", Repo: "owner/repo"}, wantOK: true},
+		{remote: "http://git.example.com:3000/owner/repo.git", want: Remote{Host: "git.example.com:3000", Repo: "owner/repo"}, wantOK: true},
+		{remote: "/srv/git/repo.git"},
+		{remote: "./repo"},
+		{remote: "file:///srv/git/repo.git"},
+		{remote: "https://github.com/"},
+		{remote: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.remote, func(t *testing.T) {
+			t.Parallel()
+			got, ok := ParseRemote(tt.remote)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("ParseRemote(%q) = %+v, %v, want %+v, %v", tt.remote, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestBranchURL(t *testing.T) {
+	t.Parallel()
+
+	custom := map[string]string{
+		"git.corp.example":  "gitlab",
+		"code.example.com":  "https://{host}/browse/{repo}?at={branch}",
+		"github.example.io": "bitbucket",
+	}
+	tests := []struct {
+		name   string
+		remote string
+		branch string
+		want   string
+	}{
+		{
+			name:   "github ssh",
+			remote: "git@github.com:owner/repo.git",
+			branch: "main",
+			want:   "https://github.com/owner/repo/tree/main",
+		},
+		{
+			name:   "github https with slashes and special characters",
+			remote: "https://github.com/owner/repo.git",
+			branch: "feat/a b#1",
+			want:   "https://github.com/owner/repo/tree/feat/a%20b%231",
+		},
+		{
+			name:   "gitlab",
+			remote: "git@gitlab.com:group/sub/repo.git",
+			branch: "main",
+			want:   "https://gitlab.com/group/sub/repo/-/tree/main",
+		},
+		{
+			name:   "bitbucket",
+			remote: "git@bitbucket.org:team/repo.git",
+			branch: "dev",
+			want:   "https://bitbucket.org/team/repo/branch/dev",
+		},
+		{
+			name:   "codeberg",
+			remote: "https://codeberg.org/owner/repo.git",
+			branch: "main",
+			want:   "https://codeberg.org/owner/repo/src/branch/main",
+		},
+		{
+			name:   "self-hosted guessed from host",
+			remote: "git@gitea.example.com:owner/repo.git",
+			branch: "main",
+			want:   "https://gitea.example.com/owner/repo/src/branch/main",
+		},
+		{
+			name:   "self-hosted forge name",
+			remote: "ssh://git@git.corp.example:2222/team/repo.git",
+			branch: "main",
+			want:   "https://git.corp.example/team/repo/-/tree/main",
+		},
+		{
+			name:   "self-hosted template",
+			remote: "git@code.example.com:team/repo.git",
+			branch: "main",
+			want:   "https://code.example.com/browse/team/repo?at=main",
+		},
+		{
+			name:   "custom entry wins over guess",
+			remote: "git@github.example.io:owner/repo.git",
+			branch: "main",
+			want:   "https://github.example.io/owner/repo/branch/main",
+		},
+		{name: "unknown host", remote: "git@example.com:owner/repo.git", branch: "main"},
+		{name: "local remote", remote: "/srv/git/repo.git", branch: "main"},
+		{name: "no branch", remote: "git@github.com:owner/repo.git"},
+		{name: "no remote", branch: "main"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := BranchURL(tt.remote, tt.branch, custom); got != tt.want {
+				t.Errorf("BranchURL(%q, %q) = %q, want %q", tt.remote, tt.branch, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		custom  map[string]string
+		wantErr bool
+	}{
+		{name: "nil"},
+		{name: "forge name", custom: map[string]string{"git.example.com": "gitea"}},
+		{name: "template", custom: map[string]string{"git.example.com": "https://{host}/{repo}/b/{branch}"}},
+		{name: "unknown forge", custom: map[string]string{"git.example.com": "sourcehut"}, wantErr: true},
+		{name: "template without branch", custom: map[string]string{"git.example.com": "https://{host}/{repo}"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if err := Validate(tt.custom); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package git
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// config is a parsed git config file: values by "section.subsection.key",
+// with the section and key lower-cased like git does. A key can repeat.
+type config map[string][]string
+
+// get returns the last value of key, like git config --get.
+func (c config) get(key string) string {
+	if v := c[key]; len(v) > 0 {
+		return v[len(v)-1]
+	}
+	return ""
+}
+
+// readConfig parses the repository's shared config file. Includes are not
+// followed. Returns an empty config when the file can't be read.
+func (r Repo) readConfig() config {
+	f, err := os.Open(filepath.Join(r.CommonDir, "config"))
+	if err != nil {
+		return config{}
+	}
+	defer func() { _ = f.Close() }()
+	return parseConfig(bufio.NewScanner(f))
+}
+
+// RemoteURL returns the URL of the named remote from the repository config,
+// or "" when there is no such remote.
+func (r Repo) RemoteURL(name string) string {
+	return r.readConfig().get("remote." + name + ".url")
+}
+
+func parseConfig(sc *bufio.Scanner) config {
+	c := config{}
+	var section string
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "[") {
+			end := strings.LastIndex(line, "]")
+			if end < 0 {
+				section = ""
+				continue
+			}
+			section = parseSection(line[1:end])
+			line = strings.TrimSpace(line[end+1:]) // a key may follow the header
+		}
+		if line == "" || line[0] == '#' || line[0] == ';' || section == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			value = "true" // a key without a value is a boolean
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		c[section+"."+key] = append(c[section+"."+key], parseValue(value))
+	}
+	return c
+}
+
+// parseSection normalizes a section header without its brackets:
+// `branch "Main"` becomes "branch.Main", and the deprecated `branch.Main`
+// becomes "branch.main".
+func parseSection(s string) string {
+	name, sub, ok := strings.Cut(s, " ")
+	if !ok {
+		return strings.ToLower(s)
+	}
+	sub = strings.TrimSpace(sub)
+	sub = strings.TrimSuffix(strings.TrimPrefix(sub, `"`), `"`)
+	sub = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(sub)
+	return strings.ToLower(name) + "." + sub
+}
+
+// parseValue unquotes a value and drops a trailing comment.
+func parseValue(s string) string {
+	var b strings.Builder
+	quoted := false
+	s = strings.TrimSpace(s)
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '"':
+			quoted = !quoted
+		case ch == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(s[i])
+			}
+		case (ch == '#' || ch == ';') && !quoted:
+			return strings.TrimSpace(b.String())
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -3,19 +3,131 @@ package git
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
 )
 
-// Branch returns the current git branch name, or "" if not in a git repo.
-// It reads .git/HEAD directly (no subprocess).
-func Branch() string {
-	data, err := os.ReadFile(".git/HEAD")
+// Repo is a git repository found by Discover.
+type Repo struct {
+	// GitDir holds the per-worktree files, such as HEAD.
+	GitDir string
+	// CommonDir holds the files shared by all worktrees, such as refs and
+	// config. It is GitDir except in linked worktrees.
+	CommonDir string
+	// WorkTree is the top of the working tree, "" for a bare repository.
+	WorkTree string
+}
+
+// Discover finds the repository containing dir by walking up from it, like
+// git does, without running git. A .git file, as in linked worktrees and
+// submodules, is followed to the git directory it names, and a commondir
+// file to the shared git directory. GIT_DIR and GIT_WORK_TREE from getenv
+// take precedence. An empty dir means the process working directory.
+func Discover(dir string, getenv func(string) string) (Repo, bool) {
+	dir, err := filepath.Abs(dir)
 	if err != nil {
+		return Repo{}, false
+	}
+	if gitDir := getenv("GIT_DIR"); gitDir != "" {
+		workTree := getenv("GIT_WORK_TREE")
+		if workTree == "" {
+			workTree = dir
+		}
+		return open(absFrom(dir, gitDir), absFrom(dir, workTree))
+	}
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if fi, err := os.Stat(dotGit); err == nil {
+			if !fi.IsDir() {
+				dotGit = readGitFile(dotGit)
+			}
+			if dotGit != "" {
+				return open(dotGit, dir)
+			}
+		}
+		if isBare(dir) {
+			return open(dir, "")
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return Repo{}, false
+		}
+		dir = parent
+	}
+}
+
+// open returns the repository with the git directory gitDir, resolving its
+// common directory.
+func open(gitDir, workTree string) (Repo, bool) {
+	if !isGitDir(gitDir) {
+		return Repo{}, false
+	}
+	r := Repo{GitDir: gitDir, CommonDir: gitDir, WorkTree: workTree}
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		if common := strings.TrimSpace(string(data)); common != "" {
+			r.CommonDir = absFrom(gitDir, common)
+		}
+	}
+	return r, true
+}
+
+// readGitFile returns the git directory a .git file points to with a
+// "gitdir: <path>" line, or "" when it is not a valid .git file.
+func readGitFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return ""
+	}
+	return absFrom(filepath.Dir(path), strings.TrimSpace(gitDir))
+}
+
+// isGitDir reports whether dir looks like a git directory: it has a HEAD
+// file, which every git directory has, including linked worktrees.
+func isGitDir(dir string) bool {
+	fi, err := os.Stat(filepath.Join(dir, "HEAD"))
+	return err == nil && fi.Mode().IsRegular()
+}
+
+// isBare reports whether dir is a bare repository: a git directory with
+// its own objects and refs.
+func isBare(dir string) bool {
+	for _, name := range []string{"objects", "refs"} {
+		if fi, err := os.Stat(filepath.Join(dir, name)); err != nil || !fi.IsDir() {
+			return false
+		}
+	}
+	return isGitDir(dir)
+}
+
+// absFrom resolves path relative to base unless it is absolute.
+func absFrom(base, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(base, path)
+}
+
+// Branch returns the current git branch name, or "" when HEAD is detached or
+// there is no repository. It reads HEAD directly (no subprocess). During a
+// rebase, which detaches HEAD, it returns the branch being rebased.
+func (r Repo) Branch() string {
+	if r.GitDir == "" {
 		return ""
 	}
-	s := strings.TrimSpace(string(data))
-	if after, ok := strings.CutPrefix(s, "ref: refs/heads/"); ok {
-		return after
+	for _, name := range []string{"HEAD", "rebase-merge/head-name", "rebase-apply/head-name"} {
+		data, err := os.ReadFile(filepath.Join(r.GitDir, name))
+		if err != nil {
+			continue
+		}
+		s := strings.TrimSpace(string(data))
+		s = strings.TrimPrefix(s, "ref: ") // only HEAD has the prefix
+		if after, ok := strings.CutPrefix(s, "refs/heads/"); ok {
+			return after
+		}
 	}
-	return "" // detached HEAD or bare repo
+	return "" // detached HEAD
 }
@@ -3,23 +3,19 @@ package git
 import (
 	"os"
 	"os/exec"
+	"path/filepath"
 	"testing"
 )
 
-func TestBranch(t *testing.T) {
-	tmp := t.TempDir()
-
-	// Save and restore working directory.
-	orig, err := os.Getwd()
+// initRepo creates a git repository with one commit in a temporary
+// directory and returns the directory and a function running git in it.
+func initRepo(t *testing.T) (string, func(args ...string)) {
+	t.Helper()
+	// Resolve symlinks so paths compare equal to git's, e.g. /private/var on macOS.
+	tmp, err := filepath.EvalSymlinks(t.TempDir())
 	if err != nil {
 		t.Fatal(err)
 	}
-	t.Cleanup(func() { _ = os.Chdir(orig) })
-	if err := os.Chdir(tmp); err != nil {
-		t.Fatal(err)
-	}
-
-	// Initialize a real git repo so .git/HEAD is created by git itself.
 	run := func(args ...string) {
 		t.Helper()
 		cmd := exec.CommandContext(t.Context(), "git", args...)
@@ -31,42 +27,167 @@ func TestBranch(t *testing.T) {
 	run("init", "-b", "main")
 	run("config", "user.email", "test@test.com")
 	run("config", "user.name", "Test")
+	run("commit", "--allow-empty", "-m", "init")
+	return tmp, run
+}
 
-	t.Run("default branch", func(t *testing.T) {
-		got := Branch()
-		if got != "main" {
-			t.Errorf("Branch() = %q, want %q", got, "main")
-		}
-	})
+func noEnv(string) string { return "" }
 
-	t.Run("branch with slashes", func(t *testing.T) {
-		run("switch", "-c", "feat/my-feature")
-		got := Branch()
-		if got != "feat/my-feature" {
-			t.Errorf("Branch() = %q, want %q", got, "feat/my-feature")
-		}
-	})
-
-	t.Run("detached HEAD", func(t *testing.T) {
-		// Need a commit to detach from.
-		run("commit", "--allow-empty", "-m", "init")
-		run("switch", "--detach")
-		got := Branch()
-		if got != "" {
-			t.Errorf("Branch() = %q, want empty string", got)
+func TestBranch(t *testing.T) {
+	t.Parallel()
+
+	// Initialize a real git repo so HEAD is written by git itself.
+	tmp, run := initRepo(t)
+	branch := func() string {
+		t.Helper()
+		repo, ok := Discover(tmp, noEnv)
+		if !ok {
+			t.Fatalf("Discover(%q) found no repository", tmp)
 		}
-	})
+		return repo.Branch()
+	}
+
+	if got := branch(); got != "main" {
+		t.Errorf("Branch() = %q, want %q", got, "main")
+	}
+
+	run("switch", "-c", "feat/my-feature")
+	if got := branch(); got != "feat/my-feature" {
+		t.Errorf("Branch() = %q, want %q", got, "feat/my-feature")
+	}
+
+	run("switch", "--detach")
+	if got := branch(); got != "" {
+		t.Errorf("Branch() with detached HEAD = %q, want empty string", got)
+	}
+
+	// A rebase detaches HEAD but names the branch being rebased.
+	rebase := filepath.Join(tmp, ".git", "rebase-merge")
+	if err := os.Mkdir(rebase, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(rebase, "head-name"), "refs/heads/feat/my-feature\n")
+	if got := branch(); got != "feat/my-feature" {
+		t.Errorf("Branch() during rebase = %q, want %q", got, "feat/my-feature")
+	}
+
+	if got := (Repo{}).Branch(); got != "" {
+		t.Errorf("Branch() without repository = %q, want empty string", got)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	t.Parallel()
+
+	tmp, run := initRepo(t)
+	gitDir := filepath.Join(tmp, ".git")
+	sub := filepath.Join(tmp, "a", "b")
+	if err := os.MkdirAll(sub, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	// A linked worktree, next to the main one.
+	worktree := tmp + "-wt"
+	t.Cleanup(func() { _ = os.RemoveAll(worktree) })
+	run("worktree", "add", "-b", "wt", worktree)
 
-	t.Run("no git directory", func(t *testing.T) {
-		noGit := t.TempDir()
-		if err := os.Chdir(noGit); err != nil {
+	// A submodule-like checkout whose .git file points into the parent's
+	// .git/modules with a relative path.
+	module := filepath.Join(tmp, "mod")
+	moduleGitDir := filepath.Join(gitDir, "modules", "mod")
+	for _, dir := range []string{module, filepath.Join(moduleGitDir, "refs"), filepath.Join(moduleGitDir, "objects")} {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
 			t.Fatal(err)
 		}
-		// Chdir back before TempDir cleanup — Windows can't remove the cwd.
-		t.Cleanup(func() { _ = os.Chdir(orig) })
-		got := Branch()
-		if got != "" {
-			t.Errorf("Branch() = %q, want empty string", got)
-		}
-	})
+	}
+	writeFile(t, filepath.Join(moduleGitDir, "HEAD"), "ref: refs/heads/trunk\n")
+	writeFile(t, filepath.Join(module, ".git"), "gitdir: ../.git/modules/mod\n")
+
+	noRepo := t.TempDir()
+
+	tests := []struct {
+		name   string
+		dir    string
+		env    map[string]string
+		want   Repo
+		branch string
+		wantOK bool
+	}{
+		{
+			name:   "top level",
+			dir:    tmp,
+			want:   Repo{GitDir: gitDir, CommonDir: gitDir, WorkTree: tmp},
+			branch: "main",
+			wantOK: true,
+		},
+		{
+			name:   "subdirectory",
+			dir:    sub,
+			want:   Repo{GitDir: gitDir, CommonDir: gitDir, WorkTree: tmp},
+			branch: "main",
+			wantOK: true,
+		},
+		{
+			name:   "linked worktree",
+			dir:    worktree,
+			want:   Repo{GitDir: filepath.Join(gitDir, "worktrees", filepath.Base(worktree)), CommonDir: gitDir, WorkTree: worktree},
+			branch: "wt",
+			wantOK: true,
+		},
+		{
+			name:   "submodule",
+			dir:    module,
+			want:   Repo{GitDir: moduleGitDir, CommonDir: moduleGitDir, WorkTree: module},
+			branch: "trunk",
+			wantOK: true,
+		},
+		{
+			name:   "bare repository",
+			dir:    moduleGitDir,
+			want:   Repo{GitDir: moduleGitDir, CommonDir: moduleGitDir},
+			branch: "trunk",
+			wantOK: true,
+		},
+		{
+			name:   "GIT_DIR",
+			dir:    noRepo,
+			env:    map[string]string{"GIT_DIR": moduleGitDir},
+			want:   Repo{GitDir: moduleGitDir, CommonDir: moduleGitDir, WorkTree: noRepo},
+			branch: "trunk",
+			wantOK: true,
+		},
+		{
+			name:   "GIT_DIR and GIT_WORK_TREE",
+			dir:    sub,
+			env:    map[string]string{"GIT_DIR": "../../.git", "GIT_WORK_TREE": tmp},
+			want:   Repo{GitDir: gitDir, CommonDir: gitDir, WorkTree: tmp},
+			branch: "main",
+			wantOK: true,
+		},
+		{
+			name: "GIT_DIR without repository",
+			dir:  tmp,
+			env:  map[string]string{"GIT_DIR": noRepo},
+		},
+		{name: "no repository", dir: noRepo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, ok := Discover(tt.dir, func(k string) string { return tt.env[k] })
+			if ok != tt.wantOK || got != tt.want {
+				t.Fatalf("Discover(%q) = %+v, %v, want %+v, %v", tt.dir, got, ok, tt.want, tt.wantOK)
+			}
+			if b := got.Branch(); b != tt.branch {
+				t.Errorf("Branch() = %q, want %q", b, tt.branch)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
 }
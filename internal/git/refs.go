@@ -0,0 +1,365 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fredrikaverpil/claudeline/internal/jsonfile"
+)
+
+// maxSymrefDepth bounds chains of symbolic refs, like git does.
+const maxSymrefDepth = 5
+
+// ResolveRef returns the object name a ref such as "HEAD" or
+// "refs/remotes/origin/main" points to, following symbolic refs. Loose refs
+// take precedence over packed-refs.
+func (r Repo) ResolveRef(name string) (string, bool) {
+	for range maxSymrefDepth {
+		target, ok := r.readRef(name)
+		if !ok {
+			return "", false
+		}
+		sym, isSym := strings.CutPrefix(target, "ref: ")
+		if !isSym {
+			return target, isHash(target)
+		}
+		name = strings.TrimSpace(sym)
+	}
+	return "", false
+}
+
+// readRef returns the raw content of a loose ref, or its packed value.
+func (r Repo) readRef(name string) (string, bool) {
+	// HEAD and other pseudo refs belong to the worktree, refs/ are shared.
+	dir := r.CommonDir
+	if !strings.HasPrefix(name, "refs/") {
+		dir = r.GitDir
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name))); err == nil {
+		return strings.TrimSpace(string(data)), true
+	}
+	return r.packedRef(name)
+}
+
+// packedRef looks up a ref in packed-refs, where each line is
+// "<object name> <ref>". Peeled lines (^) and comments (#) are skipped.
+func (r Repo) packedRef(name string) (string, bool) {
+	data, err := os.ReadFile(filepath.Join(r.CommonDir, "packed-refs"))
+	if err != nil {
+		return "", false
+	}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		hash, ref, ok := strings.Cut(sc.Text(), " ")
+		if ok && ref == name && hash != "" && hash[0] != '#' && hash[0] != '^' {
+			return hash, true
+		}
+	}
+	return "", false
+}
+
+// isHash reports whether s is a SHA-1 or SHA-256 object name.
+func isHash(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Detached returns where a detached HEAD points: the name of a tag pointing
+// at the commit, or else its abbreviated object name. Returns "" when HEAD is
+// on a branch or does not point to a commit.
+func (r Repo) Detached() string {
+	if r.GitDir == "" || r.Branch() != "" {
+		return ""
+	}
+	hash, ok := r.ResolveRef("HEAD")
+	if !ok {
+		return ""
+	}
+	if tag := r.tagFor(hash); tag != "" {
+		return tag
+	}
+	return "@" + hash[:shortHashLen]
+}
+
+// shortHashLen is the length of abbreviated object names, git's default.
+const shortHashLen = 7
+
+// tagFor returns the name of a tag pointing at the commit hash, or "" when
+// there is none. Tags in packed-refs are checked first, as they take a single
+// read; loose tags are only walked when none of them match. Among several
+// matches the first in name order wins. Annotated tags are peeled through
+// packed-refs, or by reading the tag object when it is a loose object.
+func (r Repo) tagFor(hash string) string {
+	if tag := r.packedTagFor(hash); tag != "" {
+		return tag
+	}
+	return r.looseTagFor(hash)
+}
+
+// packedTagFor returns the first tag in packed-refs pointing at the commit
+// hash. Tags that also exist as loose refs are skipped, since the loose ref
+// takes precedence and may point elsewhere.
+func (r Repo) packedTagFor(hash string) string {
+	data, err := os.ReadFile(filepath.Join(r.CommonDir, "packed-refs"))
+	if err != nil {
+		return ""
+	}
+	var tags []string
+	// A peeled line "^<commit>" follows the annotated tag it belongs to.
+	var last string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if peeled, ok := strings.CutPrefix(line, "^"); ok {
+			if peeled == hash && last != "" {
+				tags = append(tags, last)
+			}
+			continue
+		}
+		last = ""
+		target, ref, ok := strings.Cut(line, " ")
+		if name, isTag := strings.CutPrefix(ref, "refs/tags/"); ok && isTag {
+			last = name
+			if target == hash {
+				tags = append(tags, name)
+			}
+		}
+	}
+	slices.Sort(tags)
+	for _, tag := range tags {
+		if _, err := os.Stat(filepath.Join(r.CommonDir, "refs", "tags", filepath.FromSlash(tag))); err != nil {
+			return tag
+		}
+	}
+	return ""
+}
+
+// looseTagFor returns the first loose tag pointing at the commit hash,
+// stopping the walk at the first match.
+func (r Repo) looseTagFor(hash string) string {
+	var tag string
+	root := filepath.Join(r.CommonDir, "refs", "tags")
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		target := strings.TrimSpace(string(data))
+		if target != hash && r.peelLoose(target) != hash {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		tag = filepath.ToSlash(rel)
+		return fs.SkipAll
+	})
+	return tag
+}
+
+// maxTagObject bounds how much of a tag object is read to find its target.
+const maxTagObject = 512
+
+// peelLoose returns the object an annotated tag points to when the tag is
+// stored as a loose object, or "" otherwise.
+func (r Repo) peelLoose(hash string) string {
+	if !isHash(hash) {
+		return ""
+	}
+	f, err := os.Open(filepath.Join(r.CommonDir, "objects", hash[:2], hash[2:]))
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = f.Close() }()
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = zr.Close() }()
+	buf := make([]byte, maxTagObject)
+	n, _ := io.ReadFull(zr, buf)
+	// A loose object is "<type> <size>\x00<content>", and a tag's content
+	// starts with "object <hash>\n".
+	header, content, ok := bytes.Cut(buf[:n], []byte{0})
+	if !ok || !bytes.HasPrefix(header, []byte("tag ")) {
+		return ""
+	}
+	line, _, _ := bytes.Cut(content, []byte{'\n'})
+	target, ok := bytes.CutPrefix(line, []byte("object "))
+	if !ok || !isHash(string(target)) {
+		return ""
+	}
+	return string(target)
+}
+
+// Upstream returns the remote-tracking ref the branch merges from, from
+// branch.<name>.remote and branch.<name>.merge in the repository config,
+// mapped through the remote's fetch refspecs. For a branch tracking a local
+// branch (remote "."), it is the merge ref itself.
+func (r Repo) Upstream(branch string) (string, bool) {
+	if branch == "" {
+		return "", false
+	}
+	cfg := r.readConfig()
+	remote := cfg.get("branch." + branch + ".remote")
+	merge := cfg.get("branch." + branch + ".merge")
+	if remote == "" || merge == "" {
+		return "", false
+	}
+	if remote == "." {
+		return merge, true
+	}
+	for _, spec := range cfg["remote."+remote+".fetch"] {
+		if ref, ok := mapRefspec(spec, merge); ok {
+			return ref, true
+		}
+	}
+	return "", false
+}
+
+// mapRefspec maps ref through a fetch refspec such as
+// "+refs/heads/*:refs/remotes/origin/*". Reports false when the refspec does
+// not match.
+func mapRefspec(spec, ref string) (string, bool) {
+	spec = strings.TrimPrefix(spec, "+")
+	src, dst, ok := strings.Cut(spec, ":")
+	if !ok || dst == "" || strings.HasPrefix(src, "^") {
+		return "", false
+	}
+	srcPrefix, srcSuffix, srcGlob := strings.Cut(src, "*")
+	if !srcGlob {
+		if ref != src {
+			return "", false
+		}
+		return dst, true
+	}
+	dstPrefix, dstSuffix, dstGlob := strings.Cut(dst, "*")
+	if !dstGlob || !strings.HasPrefix(ref, srcPrefix) || !strings.HasSuffix(ref, srcSuffix) ||
+		len(ref) < len(srcPrefix)+len(srcSuffix) {
+		return "", false
+	}
+	match := ref[len(srcPrefix) : len(ref)-len(srcSuffix)]
+	return dstPrefix + match + dstSuffix, true
+}
+
+const (
+	// revListTimeout bounds git rev-list on long diverged histories.
+	revListTimeout = 500 * time.Millisecond
+
+	// Counts between two commits never change; they expire only to keep
+	// the cache small. Failures are retried after ttlFail.
+	ttlCounts = time.Hour
+)
+
+// counts is the on-disk cache format for ahead/behind counts, keyed by
+// "<head>...<upstream>".
+type counts struct {
+	Entries map[string]countsEntry `json:"entries"`
+}
+
+type countsEntry struct {
+	Timestamp int64 `json:"timestamp"`
+	OK        bool  `json:"ok"`
+	Ahead     int   `json:"ahead"`
+	Behind    int   `json:"behind"`
+}
+
+// ErrNoUpstream is returned by AheadBehind when the branch has no upstream,
+// or it was never fetched.
+var ErrNoUpstream = errors.New("no upstream")
+
+// AheadBehind returns how many commits HEAD has that the current branch's
+// upstream does not (ahead), and the reverse (behind). Refs are read
+// directly; only when HEAD and the upstream differ is git rev-list run,
+// bounded by a timeout, with the counts cached in cachePath.
+func (r Repo) AheadBehind(ctx context.Context, cachePath string) (ahead, behind int, err error) {
+	upstream, ok := r.Upstream(r.Branch())
+	if !ok {
+		return 0, 0, ErrNoUpstream
+	}
+	head, ok := r.ResolveRef("HEAD")
+	if !ok {
+		return 0, 0, errors.New("HEAD does not point to a commit")
+	}
+	base, ok := r.ResolveRef(upstream)
+	if !ok {
+		return 0, 0, ErrNoUpstream
+	}
+	if head == base {
+		return 0, 0, nil
+	}
+
+	key := head + "..." + base
+	cache, _ := jsonfile.Read[counts](cachePath)
+	if cache == nil || cache.Entries == nil {
+		cache = &counts{Entries: map[string]countsEntry{}}
+	}
+	now := time.Now()
+	if e, found := cache.Entries[key]; found {
+		age := now.Sub(time.Unix(e.Timestamp, 0))
+		if e.OK && age < ttlCounts {
+			return e.Ahead, e.Behind, nil
+		}
+		if !e.OK && age < ttlFail {
+			return 0, 0, errCachedFailure
+		}
+	}
+
+	ahead, behind, err = r.revListCount(ctx, key)
+	cache.Entries[key] = countsEntry{Timestamp: now.Unix(), OK: err == nil, Ahead: ahead, Behind: behind}
+	for k, e := range cache.Entries {
+		if now.Sub(time.Unix(e.Timestamp, 0)) >= ttlCounts {
+			delete(cache.Entries, k)
+		}
+	}
+	jsonfile.Write(cachePath, cache)
+	return ahead, behind, err
+}
+
+// revListCount runs git rev-list --left-right --count on a symmetric
+// difference "a...b".
+func (r Repo) revListCount(ctx context.Context, symmetric string) (left, right int, err error) {
+	ctx, cancel := context.WithTimeout(ctx, revListTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", "--git-dir="+r.GitDir,
+		"rev-list", "--left-right", "--count", symmetric, "--")
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, 0, fmt.Errorf("git rev-list: %w", err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("git rev-list: unexpected output %q", out)
+	}
+	if left, err = strconv.Atoi(fields[0]); err != nil {
+		return 0, 0, fmt.Errorf("git rev-list: %w", err)
+	}
+	if right, err = strconv.Atoi(fields[1]); err != nil {
+		return 0, 0, fmt.Errorf("git rev-list: %w", err)
+	}
+	return left, right, nil
+}
@@ -0,0 +1,203 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	t.Parallel()
+
+	input := `# comment
+[core]
+	bare = false
+[remote "origin"]
+	url = git@github.com:owner/repo.git
+	fetch = +refs/heads/*:refs/remotes/origin/*
+	fetch = +refs/tags/*:refs/tags/* ; second fetch
+[branch "Feat/X"]
+	remote = origin
+	Merge = "refs/heads/feat/x" # quoted
+[Branch.Legacy]
+	remote = .
+[alias]
+	lg
+`
+	got := parseConfig(bufio.NewScanner(strings.NewReader(input)))
+	want := config{
+		"core.bare":            {"false"},
+		"remote.origin.url":    {"git@github.com:owner/repo.git"},
+		"remote.origin.fetch":  {"+refs/heads/*:refs/remotes/origin/*", "+refs/tags/*:refs/tags/*"},
+		"branch.Feat/X.remote": {"origin"},
+		"branch.Feat/X.merge":  {"refs/heads/feat/x"},
+		"branch.legacy.remote": {"."},
+		"alias.lg":             {"true"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseConfig() =\n  %q\nwant\n  %q", got, want)
+	}
+}
+
+func TestMapRefspec(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		spec   string
+		ref    string
+		want   string
+		wantOK bool
+	}{
+		{spec: "+refs/heads/*:refs/remotes/origin/*", ref: "refs/heads/feat/x", want: "refs/remotes/origin/feat/x", wantOK: true},
+		{spec: "refs/heads/main:refs/remotes/origin/main", ref: "refs/heads/main", want: "refs/remotes/origin/main", wantOK: true},
+		{spec: "refs/heads/main:refs/remotes/origin/main", ref: "refs/heads/dev"},
+		{spec: "+refs/tags/*:refs/tags/*", ref: "refs/heads/main"},
+		{spec: "^refs/heads/tmp/*", ref: "refs/heads/tmp/x"},
+		{spec: "refs/heads/*", ref: "refs/heads/main"},
+	}
+	for _, tt := range tests {
+		got, ok := mapRefspec(tt.spec, tt.ref)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("mapRefspec(%q, %q) = %q, %v, want %q, %v", tt.spec, tt.ref, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestResolveRef(t *testing.T) {
+	t.Parallel()
+
+	gitDir := t.TempDir()
+	const (
+		loose  = "1111111111111111111111111111111111111111"
+		packed = "2222222222222222222222222222222222222222"
+		stale  = "3333333333333333333333333333333333333333"
+	)
+	if err := os.MkdirAll(filepath.Join(gitDir, "refs", "heads"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(gitDir, "HEAD"), "ref: refs/heads/main\n")
+	writeFile(t, filepath.Join(gitDir, "refs", "heads", "main"), loose+"\n")
+	writeFile(t, filepath.Join(gitDir, "packed-refs"), "# pack-refs with: peeled fully-peeled sorted\n"+
+		stale+" refs/heads/main\n"+
+		packed+" refs/tags/v1\n"+
+		"^"+stale+"\n"+
+		packed+" refs/remotes/origin/main\n")
+	repo := Repo{GitDir: gitDir, CommonDir: gitDir}
+
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{name: "HEAD", want: loose, wantOK: true},
+		{name: "refs/heads/main", want: loose, wantOK: true},
+		{name: "refs/remotes/origin/main", want: packed, wantOK: true},
+		{name: "refs/heads/missing"},
+	}
+	for _, tt := range tests {
+		got, ok := repo.ResolveRef(tt.name)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ResolveRef(%q) = %q, %v, want %q, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRepo_AheadBehind(t *testing.T) {
+	t.Parallel()
+
+	tmp, run := initRepo(t)
+	repo, ok := Discover(tmp, noEnv)
+	if !ok {
+		t.Fatal("Discover() found no repository")
+	}
+	cachePath := filepath.Join(t.TempDir(), "aheadbehind.json")
+
+	if _, _, err := repo.AheadBehind(t.Context(), cachePath); !errors.Is(err, ErrNoUpstream) {
+		t.Fatalf("AheadBehind() without upstream error = %v, want ErrNoUpstream", err)
+	}
+
+	// Track a remote-tracking branch at the current commit, then pack it.
+	run("config", "remote.origin.fetch", "+refs/heads/*:refs/remotes/origin/*")
+	run("config", "branch.main.remote", "origin")
+	run("config", "branch.main.merge", "refs/heads/main")
+	run("update-ref", "refs/remotes/origin/main", "HEAD")
+	run("pack-refs", "--all")
+	if ahead, behind, err := repo.AheadBehind(t.Context(), cachePath); err != nil || ahead != 0 || behind != 0 {
+		t.Errorf("AheadBehind() in sync = %d, %d, %v, want 0, 0, nil", ahead, behind, err)
+	}
+
+	// Diverge: one commit only upstream, two only local.
+	run("commit", "--allow-empty", "-m", "upstream")
+	run("update-ref", "refs/remotes/origin/main", "HEAD")
+	run("reset", "--hard", "HEAD~1")
+	run("commit", "--allow-empty", "-m", "local 1")
+	run("commit", "--allow-empty", "-m", "local 2")
+	if ahead, behind, err := repo.AheadBehind(t.Context(), cachePath); err != nil || ahead != 2 || behind != 1 {
+		t.Errorf("AheadBehind() diverged = %d, %d, %v, want 2, 1, nil", ahead, behind, err)
+	}
+}
+
+func TestRepo_Detached(t *testing.T) {
+	t.Parallel()
+
+	tmp, run := initRepo(t)
+	repo, ok := Discover(tmp, noEnv)
+	if !ok {
+		t.Fatal("Discover() found no repository")
+	}
+	if got := repo.Detached(); got != "" {
+		t.Errorf("Detached() on a branch = %q, want empty string", got)
+	}
+
+	run("switch", "--detach")
+	head, _ := repo.ResolveRef("HEAD")
+	if got, want := repo.Detached(), "@"+head[:7]; got != want {
+		t.Errorf("Detached() = %q, want %q", got, want)
+	}
+
+	// Annotated tags point at a tag object that is peeled to the commit.
+	run("tag", "-a", "-m", "release", "v1.0.0")
+	if got := repo.Detached(); got != "v1.0.0" {
+		t.Errorf("Detached() with loose annotated tag = %q, want %q", got, "v1.0.0")
+	}
+
+	// Lightweight tags point at the commit; the first name in order wins.
+	run("tag", "release/a")
+	if got := repo.Detached(); got != "release/a" {
+		t.Errorf("Detached() with lightweight tag = %q, want %q", got, "release/a")
+	}
+
+	run("tag", "-d", "release/a")
+	run("pack-refs", "--all")
+	run("gc", "--quiet")
+	if got := repo.Detached(); got != "v1.0.0" {
+		t.Errorf("Detached() with packed annotated tag = %q, want %q", got, "v1.0.0")
+	}
+
+	// Packed tags are preferred over loose ones.
+	run("tag", "a")
+	if got := repo.Detached(); got != "v1.0.0" {
+		t.Errorf("Detached() with packed and loose tags = %q, want %q", got, "v1.0.0")
+	}
+}
+
+func TestRepo_RemoteURL(t *testing.T) {
+	t.Parallel()
+
+	tmp, run := initRepo(t)
+	repo, ok := Discover(tmp, noEnv)
+	if !ok {
+		t.Fatal("Discover() found no repository")
+	}
+	if got := repo.RemoteURL("origin"); got != "" {
+		t.Errorf("RemoteURL() without remote = %q, want empty string", got)
+	}
+	run("remote", "add", "origin", "git@github.com:owner/repo.git")
+	if got, want := repo.RemoteURL("origin"), "git@github.com:owner/repo.git"; got != want {
+		t.Errorf("RemoteURL() = %q, want %q", got, want)
+	}
+}
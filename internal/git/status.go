@@ -0,0 +1,151 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/fredrikaverpil/claudeline/internal/jsonfile"
+)
+
+const (
+	// statusTimeout bounds git status, which can be slow in large repositories.
+	statusTimeout = 500 * time.Millisecond
+
+	ttlOK   = 5 * time.Second
+	ttlFail = 30 * time.Second
+)
+
+// Status is the state of a working tree, from git status.
+type Status struct {
+	Modified  bool `json:"modified"`  // tracked files changed but not staged
+	Staged    bool `json:"staged"`    // changes staged for commit
+	Untracked bool `json:"untracked"` // untracked files
+	Conflicts bool `json:"conflicts"` // unmerged paths
+}
+
+// statusCache is the on-disk cache format, keyed by working tree.
+type statusCache struct {
+	Repos map[string]statusEntry `json:"repos"`
+}
+
+// statusEntry is one working tree's cached status.
+type statusEntry struct {
+	Timestamp int64  `json:"timestamp"`
+	OK        bool   `json:"ok"`
+	Status    Status `json:"status"`
+}
+
+var errCachedFailure = errors.New("cached git status failure")
+
+// Status runs git status --porcelain=v2 in the working tree, bounded by a
+// timeout. Results are cached per working tree in cachePath for a few
+// seconds, and failures such as timeouts for longer.
+func (r Repo) Status(ctx context.Context, cachePath string) (Status, error) {
+	if r.WorkTree == "" {
+		return Status{}, errors.New("no working tree")
+	}
+	cache, _ := jsonfile.Read[statusCache](cachePath)
+	if cache == nil || cache.Repos == nil {
+		cache = &statusCache{Repos: map[string]statusEntry{}}
+	}
+	now := time.Now()
+	if entry, ok := cache.Repos[r.WorkTree]; ok {
+		age := now.Sub(time.Unix(entry.Timestamp, 0))
+		if entry.OK && age < ttlOK {
+			return entry.Status, nil
+		}
+		if !entry.OK && age < ttlFail {
+			return Status{}, errCachedFailure
+		}
+	}
+
+	st, err := r.status(ctx)
+	cache.Repos[r.WorkTree] = statusEntry{Timestamp: now.Unix(), OK: err == nil, Status: st}
+	for key, entry := range cache.Repos {
+		if now.Sub(time.Unix(entry.Timestamp, 0)) >= ttlFail {
+			delete(cache.Repos, key)
+		}
+	}
+	jsonfile.Write(cachePath, cache)
+	return st, err
+}
+
+// status runs git status without caching.
+func (r Repo) status(ctx context.Context) (Status, error) {
+	ctx, cancel := context.WithTimeout(ctx, statusTimeout)
+	defer cancel()
+	// --no-optional-locks keeps git from refreshing the index, which would
+	// compete with the user's own git commands for the index lock.
+	cmd := exec.CommandContext(ctx, "git", "--no-optional-locks",
+		"--git-dir="+r.GitDir, "--work-tree="+r.WorkTree,
+		"status", "--porcelain=v2", "--untracked-files=normal")
+	cmd.Dir = r.WorkTree
+	out, err := cmd.Output()
+	if err != nil {
+		return Status{}, fmt.Errorf("git status: %w", err)
+	}
+	return parseStatus(out), nil
+}
+
+// parseStatus reads git status --porcelain=v2 output.
+func parseStatus(out []byte) Status {
+	var st Status
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) < 2 {
+			continue
+		}
+		switch line[0] {
+		case '1', '2': // changed or renamed entry: "1 XY ..."
+			if len(line) < 4 {
+				continue
+			}
+			st.Staged = st.Staged || line[2] != '.'
+			st.Modified = st.Modified || line[3] != '.'
+		case 'u':
+			st.Conflicts = true
+		case '?':
+			st.Untracked = true
+		}
+	}
+	return st
+}
+
+// Operation returns the operation in progress in the working tree, such as
+// "REBASE" or "MERGE", from the marker files git leaves in the git
+// directory. Returns "" when there is none.
+func (r Repo) Operation() string {
+	if r.GitDir == "" {
+		return ""
+	}
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(r.GitDir, name))
+		return err == nil
+	}
+	switch {
+	case exists("rebase-merge"):
+		return "REBASE"
+	case exists("rebase-apply"):
+		if exists(filepath.Join("rebase-apply", "applying")) {
+			return "AM"
+		}
+		return "REBASE"
+	case exists("MERGE_HEAD"):
+		return "MERGE"
+	case exists("CHERRY_PICK_HEAD"):
+		return "CHERRY-PICK"
+	case exists("REVERT_HEAD"):
+		return "REVERT"
+	case exists("BISECT_LOG"):
+		return "BISECT"
+	}
+	return ""
+}
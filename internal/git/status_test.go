@@ -0,0 +1,122 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		out  string
+		want Status
+	}{
+		{name: "clean", out: "", want: Status{}},
+		{
+			name: "modified",
+			out:  "1 .M N... 100644 100644 100644 abc abc main.go\n",
+			want: Status{Modified: true},
+		},
+		{
+			name: "staged and modified",
+			out:  "1 MM N... 100644 100644 100644 abc def main.go\n",
+			want: Status{Modified: true, Staged: true},
+		},
+		{
+			name: "renamed",
+			out:  "2 R. N... 100644 100644 100644 abc abc R100 new.go\told.go\n",
+			want: Status{Staged: true},
+		},
+		{
+			name: "untracked and conflict",
+			out:  "? notes.txt\nu UU N... 100644 100644 100644 100644 a b c main.go\n",
+			want: Status{Untracked: true, Conflicts: true},
+		},
+		{name: "ignored", out: "! build/\n", want: Status{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := parseStatus([]byte(tt.out)); got != tt.want {
+				t.Errorf("parseStatus() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepo_Status(t *testing.T) {
+	t.Parallel()
+
+	tmp, run := initRepo(t)
+	writeFile(t, filepath.Join(tmp, "tracked.txt"), "a\n")
+	run("add", "tracked.txt")
+	run("commit", "-m", "add")
+	writeFile(t, filepath.Join(tmp, "tracked.txt"), "b\n")
+	writeFile(t, filepath.Join(tmp, "staged.txt"), "c\n")
+	run("add", "staged.txt")
+	writeFile(t, filepath.Join(tmp, "untracked.txt"), "d\n")
+
+	repo, ok := Discover(tmp, noEnv)
+	if !ok {
+		t.Fatal("Discover() found no repository")
+	}
+	cachePath := filepath.Join(t.TempDir(), "gitstatus.json")
+	got, err := repo.Status(t.Context(), cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Status{Modified: true, Staged: true, Untracked: true}); got != want {
+		t.Errorf("Status() = %+v, want %+v", got, want)
+	}
+
+	// Within the cache TTL, changes are not seen yet.
+	if err := os.Remove(filepath.Join(tmp, "untracked.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.Status(t.Context(), cachePath); err != nil || !got.Untracked {
+		t.Errorf("Status() = %+v, %v, want cached result", got, err)
+	}
+
+	if _, err := (Repo{GitDir: repo.GitDir}).Status(t.Context(), cachePath); err == nil {
+		t.Error("Status() for a bare repository error = nil, want error")
+	}
+}
+
+func TestRepo_Operation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		files []string
+		want  string
+	}{
+		{name: "none", want: ""},
+		{name: "interactive rebase", files: []string{"rebase-merge/head-name"}, want: "REBASE"},
+		{name: "rebase", files: []string{"rebase-apply/head-name"}, want: "REBASE"},
+		{name: "am", files: []string{"rebase-apply/applying"}, want: "AM"},
+		{name: "merge", files: []string{"MERGE_HEAD"}, want: "MERGE"},
+		{name: "cherry-pick", files: []string{"CHERRY_PICK_HEAD"}, want: "CHERRY-PICK"},
+		{name: "revert", files: []string{"REVERT_HEAD"}, want: "REVERT"},
+		{name: "bisect", files: []string{"BISECT_LOG"}, want: "BISECT"},
+		{name: "rebase wins over bisect", files: []string{"BISECT_LOG", "rebase-merge/head-name"}, want: "REBASE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			gitDir := t.TempDir()
+			for _, f := range tt.files {
+				path := filepath.Join(gitDir, f)
+				if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+					t.Fatal(err)
+				}
+				writeFile(t, path, "x\n")
+			}
+			if got := (Repo{GitDir: gitDir}).Operation(); got != tt.want {
+				t.Errorf("Operation() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+// Package history keeps a local, append-only log of status line samples for
+// trend analysis.
+//
+// Samples are appended to a JSONL file. When the file grows past 1 MiB it is
+// rotated: its samples are compacted to one per session per 15 minutes and
+// moved into monthly archives next to it (e.g. history-2026-10.jsonl).
+// Archives older than the retention period are removed.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// minInterval rate limits samples per session.
+	minInterval = 30 * time.Second
+	// maxSize is the size at which the active file is rotated.
+	maxSize = 1 << 20
+	// tailSize is how much of the active file is searched for a session's
+	// previous sample.
+	tailSize = 64 << 10
+	// bucket is the resolution samples are compacted to on rotation.
+	bucket = 15 * time.Minute
+	// retention is how long archives are kept.
+	retention = 400 * 24 * time.Hour
+	// monthLayout names the monthly archives.
+	monthLayout = "2006-01"
+)
+
+// Entry is a status line sample.
+type Entry struct {
+	Time      int64    `json:"t"` // Unix seconds
+	SessionID string   `json:"session,omitempty"`
+	Project   string   `json:"project,omitempty"` // project directory
+	Model     string   `json:"model,omitempty"`
+	Context   *float64 `json:"context,omitempty"`    // context window used, percent
+	Warn      bool     `json:"warn,omitempty"`       // context is past the compaction warning
+	CacheMiss bool     `json:"cache_miss,omitempty"` // last turn was a prompt cache miss
+	FiveHour  *float64 `json:"five_hour,omitempty"`  // 5-hour quota used, percent
+	SevenDay  *float64 `json:"seven_day,omitempty"`  // 7-day quota used, percent
+	CostUSD   float64  `json:"cost_usd,omitempty"`   // session cost so far
+}
+
+// same reports whether a and b hold the same sample, ignoring the time.
+func same(a, b Entry) bool {
+	return a.SessionID == b.SessionID && a.Project == b.Project && a.Model == b.Model &&
+		a.Warn == b.Warn && a.CacheMiss == b.CacheMiss && a.CostUSD == b.CostUSD &&
+		equal(a.Context, b.Context) && equal(a.FiveHour, b.FiveHour) && equal(a.SevenDay, b.SevenDay)
+}
+
+func equal(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// Append adds e to the history at path. It is skipped when it repeats the
+// session's previous sample, or when that sample is less than 30 seconds
+// old. The file is rotated when it grows past its size limit.
+func Append(path string, e Entry) error {
+	if last, ok := lastEntry(path, e.SessionID); ok {
+		if same(last, e) || time.Duration(e.Time-last.Time)*time.Second < minInterval {
+			return nil
+		}
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	// A single write keeps lines from concurrent renders intact.
+	_, err = f.Write(append(line, '\n'))
+	info, statErr := f.Stat()
+	if err := errors.Join(err, statErr, f.Close()); err != nil {
+		return err
+	}
+	if info.Size() > maxSize {
+		return rotate(path, time.Unix(e.Time, 0))
+	}
+	return nil
+}
+
+// lastEntry returns the last sample of session in the tail of the file at
+// path.
+func lastEntry(path, session string) (Entry, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Entry{}, false
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return Entry{}, false
+	}
+	offset := max(info.Size()-tailSize, 0)
+	tail := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(tail, offset); err != nil && !errors.Is(err, io.EOF) {
+		return Entry{}, false
+	}
+	lines := bytes.Split(bytes.TrimRight(tail, "\n"), []byte("\n"))
+	for i := len(lines) - 1; i >= 0; i-- {
+		var e Entry
+		if json.Unmarshal(lines[i], &e) == nil && e.SessionID == session {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// rotate moves the samples in the active file into the monthly archives,
+// compacting them, and removes archives older than the retention period.
+func rotate(path string, now time.Time) error {
+	// Renaming first means only one of several concurrent renders rotates.
+	rotating := path + "." + strconv.Itoa(os.Getpid())
+	if err := os.Rename(path, rotating); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil // another render is rotating
+		}
+		return err
+	}
+	entries, err := readFile(rotating)
+	if err != nil {
+		return err
+	}
+	months := map[string][]Entry{}
+	for _, e := range compact(entries) {
+		month := time.Unix(e.Time, 0).UTC().Format(monthLayout)
+		months[month] = append(months[month], e)
+	}
+	var errs []error
+	for month, entries := range months {
+		errs = append(errs, appendAll(archivePath(path, month), entries))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err // keep the rotated file to retry by hand
+	}
+	errs = append(errs, os.Remove(rotating))
+	archives, err := archives(path)
+	errs = append(errs, err)
+	for _, a := range archives {
+		if a.end.Before(now.Add(-retention)) {
+			errs = append(errs, os.Remove(a.path))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// compact keeps the last sample per session in each 15-minute bucket.
+func compact(entries []Entry) []Entry {
+	type key struct {
+		session string
+		bucket  int64
+	}
+	last := map[key]int{}
+	for i, e := range entries {
+		last[key{e.SessionID, e.Time / int64(bucket/time.Second)}] = i
+	}
+	var out []Entry
+	for i, e := range entries {
+		if last[key{e.SessionID, e.Time / int64(bucket/time.Second)}] == i {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func appendAll(path string, entries []Entry) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(buf.Bytes())
+	return errors.Join(err, f.Close())
+}
+
+// archive is a monthly archive file.
+type archive struct {
+	path string
+	end  time.Time // start of the following month
+}
+
+// archivePath returns the archive of the active file at path for month.
+func archivePath(path, month string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + "-" + month + filepath.Ext(path)
+}
+
+// archives returns the monthly archives of the active file at path.
+func archives(path string) ([]archive, error) {
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(path, ext) + "-"
+	matches, err := filepath.Glob(prefix + "*" + ext)
+	if err != nil {
+		return nil, err
+	}
+	var out []archive
+	for _, m := range matches {
+		// Skips other profiles' files, e.g. history-1ef5702c.jsonl.
+		month, err := time.Parse(monthLayout, strings.TrimSuffix(strings.TrimPrefix(m, prefix), ext))
+		if err != nil {
+			continue
+		}
+		out = append(out, archive{path: m, end: month.AddDate(0, 1, 0)})
+	}
+	return out, nil
+}
+
+// Read returns the samples at or after since from the history at path and
+// its archives, oldest first. Malformed lines are skipped.
+func Read(path string, since time.Time) ([]Entry, error) {
+	archives, err := archives(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	for _, a := range archives {
+		if a.end.After(since) {
+			files = append(files, a.path)
+		}
+	}
+	var out []Entry
+	for _, file := range files {
+		entries, err := readFile(file)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.Time >= since.Unix() {
+				out = append(out, e)
+			}
+		}
+	}
+	slices.SortStableFunc(out, func(a, b Entry) int { return cmp.Compare(a.Time, b.Time) })
+	return out, nil
+}
+
+// readFile reads the samples in the JSONL file at path, skipping malformed
+// lines.
+func readFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	var entries []Entry
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e Entry
+		if json.Unmarshal(sc.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return entries, nil
+}
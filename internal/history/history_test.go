@@ -0,0 +1,166 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func pct(v float64) *float64 { return &v }
+
+func TestAppend(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		writes []Entry
+		want   []int64 // times of the stored entries
+	}{
+		{
+			name: "records changes",
+			writes: []Entry{
+				{Time: 1000, SessionID: "a", Context: pct(10)},
+				{Time: 1060, SessionID: "a", Context: pct(12)},
+			},
+			want: []int64{1000, 1060},
+		},
+		{
+			name: "skips duplicates",
+			writes: []Entry{
+				{Time: 1000, SessionID: "a", Context: pct(10), CostUSD: 1.5},
+				{Time: 1600, SessionID: "a", Context: pct(10), CostUSD: 1.5},
+			},
+			want: []int64{1000},
+		},
+		{
+			name: "rate limits per session",
+			writes: []Entry{
+				{Time: 1000, SessionID: "a", Context: pct(10)},
+				{Time: 1010, SessionID: "b", Context: pct(20)},
+				{Time: 1020, SessionID: "a", Context: pct(11)},
+				{Time: 1040, SessionID: "a", Context: pct(12)},
+			},
+			want: []int64{1000, 1010, 1040},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "history.jsonl")
+			for _, e := range tt.writes {
+				if err := Append(path, e); err != nil {
+					t.Fatalf("Append() error = %v", err)
+				}
+			}
+			got, err := Read(path, time.Unix(0, 0))
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			var times []int64
+			for _, e := range got {
+				times = append(times, e.Time)
+			}
+			if !slices.Equal(times, tt.want) {
+				t.Errorf("stored times = %v, want %v", times, tt.want)
+			}
+		})
+	}
+}
+
+func TestRotate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "history.jsonl")
+	sep := time.Date(2026, 9, 30, 23, 50, 0, 0, time.UTC).Unix()
+	oct := time.Date(2026, 10, 1, 0, 5, 0, 0, time.UTC).Unix()
+	for _, e := range []Entry{
+		{Time: sep, SessionID: "a", CostUSD: 1},
+		{Time: sep + 60, SessionID: "a", CostUSD: 2}, // same bucket, kept
+		{Time: sep + 30, SessionID: "b", CostUSD: 5},
+		{Time: oct, SessionID: "a", CostUSD: 3},
+	} {
+		if err := Append(path, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// An archive past retention, and another profile's active file.
+	stale := filepath.Join(dir, "history-2024-01.jsonl")
+	other := filepath.Join(dir, "history-1ef5702c.jsonl")
+	for _, p := range []string{stale, other} {
+		if err := os.WriteFile(p, []byte(`{"t":1}`+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := rotate(path, time.Unix(oct, 0)); err != nil {
+		t.Fatalf("rotate() error = %v", err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("active file still exists after rotation")
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale archive not removed")
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("other profile's file removed: %v", err)
+	}
+	for _, month := range []string{"2026-09", "2026-10"} {
+		if _, err := os.Stat(archivePath(path, month)); err != nil {
+			t.Errorf("archive %s: %v", month, err)
+		}
+	}
+
+	got, err := Read(path, time.Unix(sep, 0))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	var costs []float64
+	for _, e := range got {
+		costs = append(costs, e.CostUSD)
+	}
+	if want := []float64{5, 2, 3}; !slices.Equal(costs, want) {
+		t.Errorf("costs after rotation = %v, want %v", costs, want)
+	}
+
+	// Appending after rotation starts a new active file.
+	if err := Append(path, Entry{Time: oct + 60, SessionID: "a", CostUSD: 4}); err != nil {
+		t.Fatal(err)
+	}
+	got, err = Read(path, time.Unix(oct, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[1].CostUSD != 4 {
+		t.Errorf("Read() after rotation = %+v", got)
+	}
+}
+
+func TestRead_skipsMalformed(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	data := `{"t":100,"session":"a"}` + "\n" + `{"t":` + "\n" + `{"t":200,"session":"a"}` + "\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(path, time.Unix(150, 0))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Time != 200 {
+		t.Errorf("Read() = %+v, want the entry at 200", got)
+	}
+}
+
+func TestRead_missing(t *testing.T) {
+	t.Parallel()
+
+	got, err := Read(filepath.Join(t.TempDir(), "history.jsonl"), time.Unix(0, 0))
+	if err != nil || len(got) != 0 {
+		t.Errorf("Read() = %v, %v, want no entries and no error", got, err)
+	}
+}
@@ -4,6 +4,7 @@ package jsonfile
 import (
 	"encoding/json"
 	"os"
+	"strconv"
 )
 
 // Read reads and unmarshals a JSON file into T.
@@ -27,3 +28,21 @@ func Write[T any](path string, v T) {
 	}
 	_ = os.WriteFile(path, data, 0o600)
 }
+
+// WriteAtomic marshals v as JSON and writes it to a temporary file renamed
+// to path, so a crash never leaves a truncated file.
+func WriteAtomic[T any](path string, v T) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp := path + "." + strconv.Itoa(os.Getpid())
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
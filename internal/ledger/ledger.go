@@ -0,0 +1,142 @@
+// Package ledger tracks cumulative cost per project across Claude Code
+// sessions and restarts.
+//
+// Claude Code reports a session's cost since the process started, so the
+// ledger remembers the last cost seen per session and adds only increases.
+// A lower cost than last seen means the session was restarted (e.g. resumed
+// in a new process), and counts from zero again.
+package ledger
+
+import (
+	"time"
+
+	"github.com/fredrikaverpil/claudeline/internal/budget"
+	"github.com/fredrikaverpil/claudeline/internal/filelock"
+	"github.com/fredrikaverpil/claudeline/internal/jsonfile"
+)
+
+// sessionTTL is how long a session's last cost is remembered.
+const sessionTTL = 30 * 24 * time.Hour
+
+// ErrLocked is returned by Update when another update holds the ledger.
+var ErrLocked = filelock.ErrLocked
+
+// Ledger is the on-disk cost ledger.
+type Ledger struct {
+	Projects map[string]*Project `json:"projects"`
+	Day      Period              `json:"day"`  // across projects, for the daily budget
+	Week     Period              `json:"week"` // across projects, for the weekly budget
+}
+
+// Period is the cost incurred since the start of a local day or week.
+type Period struct {
+	Start   int64   `json:"start"` // Unix seconds; 0 until tracked
+	CostUSD float64 `json:"cost_usd"`
+}
+
+// add adds costUSD to the period starting at start, starting over when the
+// period has rolled over.
+func (p *Period) add(start time.Time, costUSD float64) {
+	if p.Start != start.Unix() {
+		*p = Period{Start: start.Unix()}
+	}
+	p.CostUSD += costUSD
+}
+
+// Totals are the cumulative costs returned by Update.
+type Totals struct {
+	Project float64 // the project's cost
+	Day     float64 // all projects' cost since the start of the local day
+	Week    float64 // all projects' cost since the start of the local week
+}
+
+// Seed returns the costs since day and week from an earlier record, such as
+// the usage history. It fills in a ledger that doesn't track them yet.
+type Seed func(day, week time.Time) (dayUSD, weekUSD float64)
+
+// Project is the cumulative cost of a project directory.
+type Project struct {
+	TotalUSD float64             `json:"total_usd"`
+	Sessions map[string]*Session `json:"sessions"`
+}
+
+// Session is the last cost seen for a session.
+type Session struct {
+	CostUSD  float64 `json:"cost_usd"`
+	LastSeen int64   `json:"last_seen"` // Unix seconds
+}
+
+// add records a session's current cost and returns the increase over the
+// session's last cost, which is also added to the day and week.
+func (l *Ledger) add(project, session string, costUSD float64, now time.Time) float64 {
+	if l.Projects == nil {
+		l.Projects = map[string]*Project{}
+	}
+	p := l.Projects[project]
+	if p == nil {
+		p = &Project{}
+		l.Projects[project] = p
+	}
+	if p.Sessions == nil {
+		p.Sessions = map[string]*Session{}
+	}
+	s := p.Sessions[session]
+	var increase float64
+	switch {
+	case s == nil:
+		s = &Session{}
+		p.Sessions[session] = s
+		increase = costUSD
+	case costUSD < s.CostUSD:
+		increase = costUSD // restarted; counts from zero
+	default:
+		increase = costUSD - s.CostUSD
+	}
+	p.TotalUSD += increase
+	l.Day.add(budget.DayStart(now), increase)
+	l.Week.add(budget.WeekStart(now), increase)
+	s.CostUSD = costUSD
+	s.LastSeen = now.Unix()
+
+	cutoff := now.Add(-sessionTTL).Unix()
+	for _, p := range l.Projects {
+		for id, s := range p.Sessions {
+			if s.LastSeen < cutoff {
+				delete(p.Sessions, id)
+			}
+		}
+	}
+	return increase
+}
+
+// Update records a session's current cost in the ledger at path and returns
+// the project's cumulative cost and the day's and week's cost. When the
+// ledger doesn't track the day and week yet, they are taken from seed.
+// Concurrent updates from other renders are serialized with a lock file; if
+// the lock can't be taken in time, Update returns the totals from the stored
+// ledger, read without the lock, along with ErrLocked, and the cost is picked
+// up by a later update.
+func Update(path, project, session string, costUSD float64, now time.Time, seed Seed) (Totals, error) {
+	unlock, lockErr := filelock.Lock(path)
+	if lockErr == nil {
+		defer unlock()
+	}
+
+	// Writes replace the file atomically, so reading without the lock
+	// sees a complete ledger.
+	var l Ledger
+	if stored, err := jsonfile.Read[Ledger](path); err == nil {
+		l = *stored
+	}
+	seeded := l.Week.Start != 0
+	l.add(project, session, costUSD, now)
+	if !seeded && seed != nil {
+		// The seed already includes this update.
+		l.Day.CostUSD, l.Week.CostUSD = seed(budget.DayStart(now), budget.WeekStart(now))
+	}
+	totals := Totals{Project: l.Projects[project].TotalUSD, Day: l.Day.CostUSD, Week: l.Week.CostUSD}
+	if lockErr != nil {
+		return totals, lockErr
+	}
+	return totals, jsonfile.WriteAtomic(path, l)
+}
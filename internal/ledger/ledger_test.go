@@ -0,0 +1,157 @@
+package ledger
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	type update struct {
+		project, session string
+		cost             float64
+		want             float64
+	}
+	tests := []struct {
+		name    string
+		updates []update
+	}{
+		{
+			name: "counts increases once",
+			updates: []update{
+				{"/p", "a", 1, 1},
+				{"/p", "a", 1, 1},
+				{"/p", "a", 2.5, 2.5},
+			},
+		},
+		{
+			name: "sums sessions",
+			updates: []update{
+				{"/p", "a", 1, 1},
+				{"/p", "b", 2, 3},
+				{"/p", "a", 1.5, 3.5},
+			},
+		},
+		{
+			name: "restart counts from zero",
+			updates: []update{
+				{"/p", "a", 4, 4},
+				{"/p", "a", 0.5, 4.5},
+				{"/p", "a", 1, 5},
+			},
+		},
+		{
+			name: "separate projects",
+			updates: []update{
+				{"/p", "a", 1, 1},
+				{"/q", "b", 2, 2},
+				{"/p", "a", 3, 3},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "ledger.json")
+			for i, u := range tt.updates {
+				got, err := Update(path, u.project, u.session, u.cost, now, nil)
+				if err != nil {
+					t.Fatalf("Update() error = %v", err)
+				}
+				if math.Abs(got.Project-u.want) > 1e-9 {
+					t.Errorf("update %d: Update() project = %v, want %v", i, got.Project, u.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAdd_prunesSessions(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	var l Ledger
+	l.add("/p", "old", 1, now.Add(-31*24*time.Hour))
+	l.add("/p", "new", 2, now)
+	if _, ok := l.Projects["/p"].Sessions["old"]; ok {
+		t.Error("session not seen for 31 days was kept")
+	}
+	if got := l.Projects["/p"].TotalUSD; got != 3 {
+		t.Errorf("TotalUSD = %v, want 3", got)
+	}
+}
+
+func TestUpdate_locked(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	if _, err := Update(path, "/p", "a", 5, time.Now(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+".lock", nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// The stored total, with this session's increase, is still returned.
+	if got, err := Update(path, "/p", "a", 6, time.Now(), nil); !errors.Is(err, ErrLocked) || got.Project != 6 {
+		t.Errorf("Update() = %+v, %v, want project 6, ErrLocked", got, err)
+	}
+
+	// A stale lock is taken over.
+	old := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(path+".lock", old, old); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Update(path, "/p", "a", 7, time.Now(), nil); err != nil || got.Project != 7 {
+		t.Errorf("Update() = %+v, %v, want project 7, nil", got, err)
+	}
+}
+
+func TestUpdate_dayAndWeek(t *testing.T) {
+	t.Parallel()
+
+	// Friday 2026-10-16; the week started on Monday 2026-10-12.
+	friday := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	var seeded []time.Time
+	seed := func(day, week time.Time) (float64, float64) {
+		seeded = append(seeded, day, week)
+		return 3, 10 // including the first update
+	}
+	tests := []struct {
+		name             string
+		project, session string
+		cost             float64
+		now              time.Time
+		wantDay          float64
+		wantWeek         float64
+	}{
+		{name: "seeded", project: "/p", session: "a", cost: 1, now: friday, wantDay: 3, wantWeek: 10},
+		{name: "increase", project: "/p", session: "a", cost: 1.5, now: friday, wantDay: 3.5, wantWeek: 10.5},
+		{name: "other project", project: "/q", session: "b", cost: 2, now: friday, wantDay: 5.5, wantWeek: 12.5},
+		{name: "next day", project: "/p", session: "a", cost: 2, now: friday.AddDate(0, 0, 1), wantDay: 0.5, wantWeek: 13},
+		{name: "next week", project: "/q", session: "b", cost: 3, now: friday.AddDate(0, 0, 3), wantDay: 1, wantWeek: 1},
+	}
+	for _, tt := range tests {
+		got, err := Update(path, tt.project, tt.session, tt.cost, tt.now, seed)
+		if err != nil {
+			t.Fatalf("%s: Update() error = %v", tt.name, err)
+		}
+		if math.Abs(got.Day-tt.wantDay) > 1e-9 || math.Abs(got.Week-tt.wantWeek) > 1e-9 {
+			t.Errorf("%s: Update() day, week = %v, %v, want %v, %v", tt.name, got.Day, got.Week, tt.wantDay, tt.wantWeek)
+		}
+	}
+	want := []time.Time{
+		time.Date(2026, 10, 16, 0, 0, 0, 0, time.Local),
+		time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local),
+	}
+	if !slices.EqualFunc(seeded, want, time.Time.Equal) {
+		t.Errorf("seeded with %v, want once with %v", seeded, want)
+	}
+}
@@ -39,6 +39,37 @@ func CacheDir() string {
 	return filepath.Join(base, "claudeline")
 }
 
+// StateDir returns the directory for data that should outlive the cache,
+// such as the usage history: $XDG_STATE_HOME/claudeline, or
+// ~/.local/state/claudeline. On Windows it is under %LocalAppData%. Falls
+// back to CacheDir when no home directory is found.
+func StateDir() string {
+	if runtime.GOOS == "windows" {
+		if dir, err := os.UserCacheDir(); err == nil {
+			return filepath.Join(dir, "claudeline")
+		}
+		return CacheDir()
+	}
+	if xdg := os.Getenv("XDG_STATE_HOME"); xdg != "" {
+		return filepath.Join(xdg, "claudeline")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return CacheDir()
+	}
+	return filepath.Join(home, ".local", "state", "claudeline")
+}
+
+// MustStateFile constructs a filepath into StateDir, incorporating a
+// configDir-based suffix like MustCacheFile.
+func MustStateFile(configDir, filename string) string {
+	name, ext, ok := strings.Cut(filename, ".")
+	if !ok {
+		panic(fmt.Sprintf("cannot cut filename: %s", filename))
+	}
+	return filepath.Join(StateDir(), name+ConfigDirSuffix(configDir)+"."+ext)
+}
+
 // MustCacheFile constructs a filepath into /tmp/claudeline, incorporating a
 // configDir-based suffix to avoid collisions between Claude Code profiles.
 func MustCacheFile(configDir, filename string) string {
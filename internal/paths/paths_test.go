@@ -2,6 +2,7 @@ package paths
 
 import (
 	"path/filepath"
+	"runtime"
 	"testing"
 )
 
@@ -113,3 +114,18 @@ func TestMustCacheFile_NoConfigDir(t *testing.T) {
 		t.Errorf("MustCacheFile(%q, %q) = %q, want %q", "", "usage.json", got, want)
 	}
 }
+
+func TestStateDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("XDG_STATE_HOME is not used on Windows")
+	}
+	t.Setenv("XDG_STATE_HOME", "/xdg/state")
+	if got, want := StateDir(), filepath.Join("/xdg/state", "claudeline"); got != want {
+		t.Errorf("StateDir() = %q, want %q", got, want)
+	}
+	configDir := "/Users/oa/.claude-work"
+	want := filepath.Join("/xdg/state", "claudeline", "history"+ConfigDirSuffix(configDir)+".jsonl")
+	if got := MustStateFile(configDir, "history.jsonl"); got != want {
+		t.Errorf("MustStateFile(%q, %q) = %q, want %q", configDir, "history.jsonl", got, want)
+	}
+}
@@ -0,0 +1,75 @@
+package render
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// BarStyle selects how a progress bar is drawn.
+type BarStyle struct {
+	Name  string // a key of barStyles
+	Width int    // cells, not counting caps
+}
+
+// DefaultBarStyle is the style of both bars when none is configured.
+var DefaultBarStyle = BarStyle{Name: "block", Width: 5}
+
+// barGlyphs are the characters of a bar style. A cell is split into
+// len(partial)+1 steps; partial[i] shows i+1 of them.
+type barGlyphs struct {
+	filled  string
+	partial []string
+	empty   string
+	// Caps are drawn at both ends, in the color of the cell next to them.
+	capLeft, capRight string
+}
+
+// barStyles are the available bar styles. The block style takes its
+// characters from the theme's glyphs so that it follows ASCII mode.
+var barStyles = map[string]barGlyphs{
+	"block": {},
+	"eighths": {
+		filled:  "█",
+		partial: []string{"▏", "▎", "▍", "▌", "▋", "▊", "▉"},
+		empty:   "░",
+	},
+	"braille": {
+		filled:  "⣿",
+		partial: []string{"⡀", "⡄", "⡆", "⡇", "⣇", "⣧", "⣷"},
+		empty:   "⣀",
+	},
+	"dots": {filled: "●", partial: []string{"◐"}, empty: "○"},
+	"pill": {
+		filled:   "█",
+		partial:  []string{"▌"},
+		empty:    "░",
+		capLeft:  "▐",
+		capRight: "▌",
+	},
+}
+
+// ParseBarStyle validates a bar style name and width.
+func ParseBarStyle(name string, width int) (BarStyle, error) {
+	if name == "" {
+		name = DefaultBarStyle.Name
+	}
+	if _, ok := barStyles[name]; !ok {
+		return BarStyle{}, fmt.Errorf("unknown bar style %q (want %s)",
+			name, strings.Join(slices.Sorted(maps.Keys(barStyles)), ", "))
+	}
+	if width < 1 {
+		return BarStyle{}, fmt.Errorf("bar width must be at least 1, got %d", width)
+	}
+	return BarStyle{Name: name, Width: width}, nil
+}
+
+// barGlyphs returns the characters for style, falling back to block.
+func (t Theme) barGlyphs(style BarStyle) barGlyphs {
+	g, ok := barStyles[style.Name]
+	if !ok || style.Name == "block" {
+		return barGlyphs{filled: t.Glyphs.BarFilled, empty: t.Glyphs.BarEmpty}
+	}
+	return g
+}
@@ -0,0 +1,65 @@
+package render
+
+import "testing"
+
+func TestBar_styles(t *testing.T) {
+	t.Parallel()
+
+	plain, err := LoadTheme("default", nil, DepthNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noColor := func(int) string { return "" }
+
+	tests := []struct {
+		name  string
+		style BarStyle
+		pct   int
+		want  string
+	}{
+		{name: "block", style: BarStyle{Name: "block", Width: 5}, pct: 42, want: "██░░░ 42%"},
+		{name: "block wide", style: BarStyle{Name: "block", Width: 10}, pct: 42, want: "████░░░░░░ 42%"},
+		{name: "eighths 1%", style: BarStyle{Name: "eighths", Width: 5}, pct: 1, want: "░░░░░ 1%"},
+		{name: "eighths 19%", style: BarStyle{Name: "eighths", Width: 5}, pct: 19, want: "▉░░░░ 19%"},
+		{name: "eighths 45%", style: BarStyle{Name: "eighths", Width: 5}, pct: 45, want: "██▎░░ 45%"},
+		{name: "eighths full", style: BarStyle{Name: "eighths", Width: 5}, pct: 100, want: "█████ 100%"},
+		{name: "braille", style: BarStyle{Name: "braille", Width: 5}, pct: 30, want: "⣿⡇⣀⣀⣀ 30%"},
+		{name: "braille 45%", style: BarStyle{Name: "braille", Width: 5}, pct: 45, want: "⣿⣿⡄⣀⣀ 45%"},
+		{name: "braille 55%", style: BarStyle{Name: "braille", Width: 5}, pct: 55, want: "⣿⣿⣧⣀⣀ 55%"},
+		{name: "dots", style: BarStyle{Name: "dots", Width: 4}, pct: 50, want: "●●○○ 50%"},
+		{name: "dots half", style: BarStyle{Name: "dots", Width: 4}, pct: 40, want: "●◐○○ 40%"},
+		{name: "pill empty", style: BarStyle{Name: "pill", Width: 3}, pct: 0, want: "▐░░░▌ 0%"},
+		{name: "pill partial", style: BarStyle{Name: "pill", Width: 3}, pct: 50, want: "▐█▌░▌ 50%"},
+		{name: "pill full", style: BarStyle{Name: "pill", Width: 3}, pct: 100, want: "▐███▌ 100%"},
+		{name: "unknown falls back to block", style: BarStyle{Name: "zigzag", Width: 5}, pct: 60, want: "███░░ 60%"},
+		{name: "clamped", style: BarStyle{Name: "eighths", Width: 2}, pct: 150, want: "██ 100%"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := plain.Bar(tt.style, tt.pct, noColor)
+			if got != tt.want {
+				t.Errorf("Bar(%+v, %d) = %q, want %q", tt.style, tt.pct, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBarStyle(t *testing.T) {
+	t.Parallel()
+
+	if got, err := ParseBarStyle("", 5); err != nil || got != DefaultBarStyle {
+		t.Errorf(`ParseBarStyle("", 5) = %+v, %v, want %+v`, got, err, DefaultBarStyle)
+	}
+	if got, err := ParseBarStyle("braille", 8); err != nil || got != (BarStyle{Name: "braille", Width: 8}) {
+		t.Errorf(`ParseBarStyle("braille", 8) = %+v, %v`, got, err)
+	}
+	if _, err := ParseBarStyle("zigzag", 5); err == nil {
+		t.Error(`ParseBarStyle("zigzag", 5) error = nil, want error`)
+	}
+	if _, err := ParseBarStyle("dots", 0); err == nil {
+		t.Error(`ParseBarStyle("dots", 0) error = nil, want error`)
+	}
+}
@@ -0,0 +1,274 @@
+package render
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ColorDepth is the number of colors a terminal can display.
+type ColorDepth int
+
+// Color depths, from no color at all to 24-bit RGB.
+const (
+	DepthNone      ColorDepth = iota // no SGR sequences at all
+	Depth16                          // the 16 ANSI colors
+	Depth256                         // the xterm 256-color palette
+	DepthTrueColor                   // 24-bit RGB
+)
+
+func (d ColorDepth) String() string {
+	switch d {
+	case DepthNone:
+		return "none"
+	case Depth16:
+		return "16"
+	case Depth256:
+		return "256"
+	case DepthTrueColor:
+		return "truecolor"
+	default:
+		return "ColorDepth(" + strconv.Itoa(int(d)) + ")"
+	}
+}
+
+// ParseColorDepth parses a color depth name: "none", "16", "256" or
+// "truecolor" (also "24bit").
+func ParseColorDepth(s string) (ColorDepth, error) {
+	switch strings.ToLower(s) {
+	case "none":
+		return DepthNone, nil
+	case "16":
+		return Depth16, nil
+	case "256":
+		return Depth256, nil
+	case "truecolor", "24bit":
+		return DepthTrueColor, nil
+	default:
+		return 0, fmt.Errorf("unknown color depth %q (want none, 16, 256 or truecolor)", s)
+	}
+}
+
+// DetectColorDepth guesses the terminal's color depth from the environment:
+// a non-empty NO_COLOR disables color (https://no-color.org), COLORTERM
+// announces truecolor, and TERM names the terminal type. When TERM is unset,
+// as can happen when the status line runs as a subprocess, 256 colors are
+// assumed.
+func DetectColorDepth(getenv func(string) string) ColorDepth {
+	if getenv("NO_COLOR") != "" {
+		return DepthNone
+	}
+	switch strings.ToLower(getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return DepthTrueColor
+	}
+	term := strings.ToLower(getenv("TERM"))
+	switch {
+	case term == "":
+		return Depth256
+	case term == "dumb":
+		return DepthNone
+	case strings.HasSuffix(term, "-direct"):
+		return DepthTrueColor
+	case strings.Contains(term, "256color"):
+		return Depth256
+	default:
+		return Depth16
+	}
+}
+
+type colorKind int
+
+const (
+	colorDefault colorKind = iota // terminal's default foreground
+	colorANSI                     // one of the 16 ANSI colors
+	colorIndexed                  // xterm 256-color palette index
+	colorRGB                      // 24-bit RGB
+)
+
+// color is a parsed color spec: a foreground color plus attributes.
+type color struct {
+	attrs []string // SGR attribute parameters, e.g. "1" for bold
+	kind  colorKind
+	index int // palette index for colorANSI (0–15) and colorIndexed (0–255)
+	rgb   [3]uint8
+}
+
+// attrNames maps attribute names to SGR parameters.
+var attrNames = map[string]string{
+	"bold":      "1",
+	"dim":       "2",
+	"italic":    "3",
+	"underline": "4",
+	"reverse":   "7",
+}
+
+// ansiNames maps the 16 ANSI color names to their palette index.
+var ansiNames = map[string]int{
+	"black":          0,
+	"red":            1,
+	"green":          2,
+	"yellow":         3,
+	"blue":           4,
+	"magenta":        5,
+	"cyan":           6,
+	"white":          7,
+	"bright-black":   8,
+	"bright-red":     9,
+	"bright-green":   10,
+	"bright-yellow":  11,
+	"bright-blue":    12,
+	"bright-magenta": 13,
+	"bright-cyan":    14,
+	"bright-white":   15,
+}
+
+// parseColor parses a color spec (see ThemeSpec).
+func parseColor(spec string) (color, error) {
+	var c color
+	setColor := func(tok string, kind colorKind) error {
+		if c.kind != colorDefault {
+			return fmt.Errorf("more than one color in %q", spec)
+		}
+		c.kind = kind
+		return nil
+	}
+	for _, tok := range strings.Fields(strings.ToLower(spec)) {
+		if tok == "none" {
+			continue
+		}
+		if p, ok := attrNames[tok]; ok {
+			c.attrs = append(c.attrs, p)
+			continue
+		}
+		var err error
+		switch hex, isHex := strings.CutPrefix(tok, "#"); {
+		case tok == "orange":
+			err = setColor(tok, colorIndexed)
+			c.index = 208
+		case isHex:
+			rgb, perr := strconv.ParseUint(hex, 16, 32)
+			if perr != nil || len(hex) != 6 {
+				return color{}, fmt.Errorf("invalid hex color %q", tok)
+			}
+			err = setColor(tok, colorRGB)
+			c.rgb = [3]uint8{uint8(rgb >> 16), uint8(rgb >> 8), uint8(rgb)}
+		default:
+			if i, ok := ansiNames[tok]; ok {
+				err = setColor(tok, colorANSI)
+				c.index = i
+				break
+			}
+			n, aerr := strconv.Atoi(tok)
+			if aerr != nil || n < 0 || n > 255 {
+				return color{}, fmt.Errorf("invalid color %q", tok)
+			}
+			err = setColor(tok, colorIndexed)
+			c.index = n
+		}
+		if err != nil {
+			return color{}, err
+		}
+	}
+	return c, nil
+}
+
+// sgr returns the ANSI sequence for c on a terminal with the given depth,
+// converting the color to the nearest one the terminal supports. Indexed
+// colors are upgraded to RGB on truecolor terminals, except for the first 16
+// whose appearance the terminal's own palette decides.
+func (c color) sgr(depth ColorDepth) string {
+	if depth == DepthNone {
+		return ""
+	}
+	params := slices.Clone(c.attrs)
+	switch c.kind {
+	case colorANSI:
+		params = append(params, ansiParam(c.index))
+	case colorIndexed:
+		switch {
+		case c.index < 16 && depth == Depth16:
+			params = append(params, ansiParam(c.index))
+		case c.index < 16:
+			params = append(params, "38;5;"+strconv.Itoa(c.index))
+		default:
+			params = append(params, rgbParam(xtermRGB(c.index), depth))
+		}
+	case colorRGB:
+		params = append(params, rgbParam(c.rgb, depth))
+	}
+	if len(params) == 0 {
+		return ""
+	}
+	return "\033[" + strings.Join(params, ";") + "m"
+}
+
+// ansiParam returns the SGR foreground parameter for ANSI color i (0–15).
+func ansiParam(i int) string {
+	if i < 8 {
+		return strconv.Itoa(30 + i)
+	}
+	return strconv.Itoa(90 + i - 8)
+}
+
+// rgbParam returns the SGR foreground parameter closest to rgb at depth.
+func rgbParam(rgb [3]uint8, depth ColorDepth) string {
+	switch depth {
+	case DepthTrueColor:
+		return fmt.Sprintf("38;2;%d;%d;%d", rgb[0], rgb[1], rgb[2])
+	case Depth256:
+		return "38;5;" + strconv.Itoa(nearest(rgb, 16, 256))
+	default:
+		return ansiParam(nearest(rgb, 0, 16))
+	}
+}
+
+// nearest returns the xterm palette index in [from, to) closest to rgb.
+func nearest(rgb [3]uint8, from, to int) int {
+	best, bestDist := from, -1
+	for i := from; i < to; i++ {
+		p := xtermRGB(i)
+		dist := 0
+		for ch := range 3 {
+			d := int(rgb[ch]) - int(p[ch])
+			dist += d * d
+		}
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+// ansiRGB holds xterm's default values for the 16 ANSI colors.
+var ansiRGB = [16][3]uint8{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// xtermRGB returns the RGB value of xterm palette index i (0–255): the 16
+// ANSI colors, a 6×6×6 color cube and a 24-step gray ramp.
+func xtermRGB(i int) [3]uint8 {
+	switch {
+	case i < 16:
+		return ansiRGB[i]
+	case i < 232:
+		levels := [6]uint8{0, 95, 135, 175, 215, 255}
+		i -= 16
+		return [3]uint8{levels[i/36], levels[i/6%6], levels[i%6]}
+	default:
+		g := uint8(8 + 10*(i-232))
+		return [3]uint8{g, g, g}
+	}
+}
+
+// paint wraps text in color. Text is returned unchanged when color is "".
+func paint(color, text string) string {
+	if color == "" {
+		return text
+	}
+	return color + text + Reset
+}
@@ -0,0 +1,153 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fredrikaverpil/claudeline/internal/stdin"
+	"github.com/fredrikaverpil/claudeline/internal/update"
+)
+
+func TestColorSGR(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		spec      string
+		none      string
+		ansi16    string
+		ansi256   string
+		truecolor string
+	}{
+		{spec: "none"},
+		{spec: "green", ansi16: Green, ansi256: Green, truecolor: Green},
+		{spec: "Bright-Blue", ansi16: BrightBlue, ansi256: BrightBlue, truecolor: BrightBlue},
+		{
+			spec:      "orange",
+			ansi16:    Yellow,
+			ansi256:   Orange,
+			truecolor: "\033[38;2;255;135;0m",
+		},
+		{spec: "9", ansi16: "\033[91m", ansi256: "\033[38;5;9m", truecolor: "\033[38;5;9m"},
+		{
+			spec:      "#d55e00",
+			ansi16:    Red,
+			ansi256:   "\033[38;5;166m",
+			truecolor: "\033[38;2;213;94;0m",
+		},
+		{
+			spec:      "bold #808080",
+			ansi16:    "\033[1;90m",
+			ansi256:   "\033[1;38;5;244m",
+			truecolor: "\033[1;38;2;128;128;128m",
+		},
+		{spec: "dim", ansi16: Dim, ansi256: Dim, truecolor: Dim},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := parseColor(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for depth, want := range map[ColorDepth]string{
+				DepthNone:      tt.none,
+				Depth16:        tt.ansi16,
+				Depth256:       tt.ansi256,
+				DepthTrueColor: tt.truecolor,
+			} {
+				if got := c.sgr(depth); got != want {
+					t.Errorf("parseColor(%q).sgr(%v) = %q, want %q", tt.spec, depth, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseColor_invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, spec := range []string{"256", "-1", "#12345g", "#fff", "chartreuse", "red blue"} {
+		if _, err := parseColor(spec); err == nil {
+			t.Errorf("parseColor(%q) error = nil, want error", spec)
+		}
+	}
+}
+
+func TestDetectColorDepth(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want ColorDepth
+	}{
+		{name: "unset", env: nil, want: Depth256},
+		{name: "no color", env: map[string]string{"NO_COLOR": "1", "COLORTERM": "truecolor"}, want: DepthNone},
+		{name: "empty no color is ignored", env: map[string]string{"NO_COLOR": "", "TERM": "xterm"}, want: Depth16},
+		{name: "colorterm", env: map[string]string{"COLORTERM": "24bit", "TERM": "xterm"}, want: DepthTrueColor},
+		{name: "direct", env: map[string]string{"TERM": "xterm-direct"}, want: DepthTrueColor},
+		{name: "256color", env: map[string]string{"TERM": "tmux-256color"}, want: Depth256},
+		{name: "basic", env: map[string]string{"TERM": "xterm"}, want: Depth16},
+		{name: "dumb", env: map[string]string{"TERM": "dumb"}, want: DepthNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := DetectColorDepth(func(key string) string { return tt.env[key] })
+			if got != tt.want {
+				t.Errorf("DetectColorDepth() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseColorDepth(t *testing.T) {
+	t.Parallel()
+
+	for _, depth := range []ColorDepth{DepthNone, Depth16, Depth256, DepthTrueColor} {
+		got, err := ParseColorDepth(depth.String())
+		if err != nil || got != depth {
+			t.Errorf("ParseColorDepth(%q) = %v, %v, want %v", depth.String(), got, err, depth)
+		}
+	}
+	if _, err := ParseColorDepth("auto"); err == nil {
+		t.Error(`ParseColorDepth("auto") error = nil, want error`)
+	}
+}
+
+func TestBuild_noColor(t *testing.T) {
+	t.Parallel()
+
+	theme, err := LoadTheme("default", nil, DepthNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pct := 42.0
+	five := 80.0
+	p := Params{
+		LoginType:      "Pro",
+		Model:          "Opus",
+		ContextUsedPct: &pct,
+		StdinRateLimits: &struct {
+			FiveHour *stdin.RateLimit `json:"five_hour"`
+			SevenDay *stdin.RateLimit `json:"seven_day"`
+		}{FiveHour: &stdin.RateLimit{UsedPercentage: &five}},
+		ShowBranch:   true,
+		Branch:       "main",
+		BranchURL:    "https://github.com/o/r/tree/main",
+		BranchMaxLen: 30,
+		Update:       &update.Response{TagName: "v9.0.0"},
+		Layout:       []string{SegmentIdentity, SegmentBranch, SegmentContext, Segment5h, SegmentUpdate},
+		Theme:        &theme,
+	}
+	// No escape sequences at all, not even hyperlinks.
+	got := strings.ReplaceAll(Build(p), "\u00A0", " ")
+	want := "Pro │ Opus │ main │ ██░░░ 42% │ ████░ 80% │ ↑"
+	if got != want {
+		t.Errorf("Build() = %q, want %q", got, want)
+	}
+}
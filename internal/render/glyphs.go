@@ -0,0 +1,85 @@
+package render
+
+// Glyphs are the symbols the status line is drawn with.
+type Glyphs struct {
+	BarFilled string // filled bar cell
+	BarEmpty  string // empty bar cell
+	BarLeft   string // drawn before a bar
+	BarRight  string // drawn after a bar
+
+	Separator    string // between segments
+	SubSeparator string // between attached segments, e.g. per-model bars
+	Ellipsis     string // replaces the middle of truncated names
+
+	Compact     string // context is approaching auto-compaction
+	Extended    string // extended context (>200k tokens)
+	CacheMiss   string // prompt cache miss
+	CacheHit    string // prefixes the session's prompt cache hit ratio
+	Times       string // prefixes a repeat count, e.g. of cache misses
+	PeakHours   string // prefixes the 5-hour bar during peak hours
+	Projected   string // prefixes the projected 5-hour quota exhaustion time
+	Update      string // a newer claudeline release is available
+	Ahead       string // prefixes commits ahead of the upstream branch
+	Behind      string // prefixes commits behind the upstream branch
+	ProjectCost string // prefixes the cumulative project cost
+	BudgetWarn  string // a cost budget is nearly used up
+	BudgetOver  string // a cost budget is used up
+
+	// Service disruption severities.
+	StatusMinor    string
+	StatusMajor    string
+	StatusCritical string
+}
+
+// UnicodeGlyphs draws with block elements and emoji.
+var UnicodeGlyphs = Glyphs{
+	BarFilled:      "█",
+	BarEmpty:       "░",
+	Separator:      " │ ",
+	SubSeparator:   " · ",
+	Ellipsis:       "…",
+	Compact:        "⚠️",
+	Extended:       "🥵",
+	CacheMiss:      "🥊",
+	CacheHit:       "⟳",
+	Times:          "×",
+	PeakHours:      "⚡️",
+	Projected:      "→",
+	Update:         "↑",
+	Ahead:          "↑",
+	Behind:         "↓",
+	ProjectCost:    "Σ",
+	BudgetWarn:     "💸",
+	BudgetOver:     "🚨",
+	StatusMinor:    "🔥▂",
+	StatusMajor:    "🔥▄▂",
+	StatusCritical: "🔥▆▄▂",
+}
+
+// ASCIIGlyphs draws with printable ASCII only, for terminals and screen
+// readers that can't handle block elements or emoji.
+var ASCIIGlyphs = Glyphs{
+	BarFilled:      "#",
+	BarEmpty:       "-",
+	BarLeft:        "[",
+	BarRight:       "]",
+	Separator:      " | ",
+	SubSeparator:   " / ",
+	Ellipsis:       "...",
+	Compact:        "!compact",
+	Extended:       ">200k",
+	CacheMiss:      "cache-miss",
+	CacheHit:       "cache:",
+	Times:          "x",
+	PeakHours:      "peak:",
+	Projected:      "->",
+	Update:         "update",
+	Ahead:          "ahead:",
+	Behind:         "behind:",
+	ProjectCost:    "project:",
+	BudgetWarn:     "!budget",
+	BudgetOver:     "!!budget",
+	StatusMinor:    "status:minor",
+	StatusMajor:    "status:major",
+	StatusCritical: "status:critical",
+}
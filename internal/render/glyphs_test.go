@@ -0,0 +1,54 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fredrikaverpil/claudeline/internal/status"
+	"github.com/fredrikaverpil/claudeline/internal/stdin"
+	"github.com/fredrikaverpil/claudeline/internal/update"
+)
+
+func TestBuild_ascii(t *testing.T) {
+	t.Parallel()
+
+	theme, err := LoadTheme("default", nil, DepthNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	theme.Glyphs = ASCIIGlyphs
+
+	pct := 85.0
+	five := 40.0
+	p := Params{
+		LoginType:         "Pro",
+		Model:             "Opus",
+		ContextUsedPct:    &pct,
+		Exceeds200kTokens: true,
+		CacheMiss:         true,
+		StdinRateLimits: &struct {
+			FiveHour *stdin.RateLimit `json:"five_hour"`
+			SevenDay *stdin.RateLimit `json:"seven_day"`
+		}{FiveHour: &stdin.RateLimit{UsedPercentage: &five}},
+		ShowBranch:   true,
+		Branch:       "feature/very-long-branch-name",
+		BranchMaxLen: 12,
+		Status:       &status.Response{},
+		Update:       &update.Response{TagName: "v1.2.3"},
+		Layout: []string{
+			SegmentIdentity, SegmentBranch, SegmentContext, Segment5h, SegmentStatus, SegmentUpdate,
+		},
+		Theme: &theme,
+	}
+	p.Status.Status.Indicator = "major"
+	got := stripANSI(strings.ReplaceAll(Build(p), " ", " "))
+	want := "Pro | Opus | feat...-name | [####-] 85% !compact >200k cache-miss | [##---] 40% | status:major | update"
+	if got != want {
+		t.Errorf("Build() =\n  %q\nwant\n  %q", got, want)
+	}
+	for _, r := range got {
+		if r > 0x7E {
+			t.Errorf("Build() contains non-ASCII rune %q", r)
+		}
+	}
+}
@@ -2,13 +2,16 @@ package render
 
 import (
 	"fmt"
+	"log"
 	"math"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/fredrikaverpil/claudeline/internal/policy"
+	"github.com/fredrikaverpil/claudeline/internal/display"
+	"github.com/fredrikaverpil/claudeline/internal/git"
 	"github.com/fredrikaverpil/claudeline/internal/status"
 	"github.com/fredrikaverpil/claudeline/internal/stdin"
 	"github.com/fredrikaverpil/claudeline/internal/update"
@@ -29,173 +32,183 @@ const (
 	Reset         = "\033[0m"
 )
 
-const barWidth = 5
+// Token count modes for Params.ContextTokens.
+const (
+	TokensTotal  = "total"  // tokens in the context window, e.g. 84k/200k
+	TokensDetail = "detail" // also input, output, cache read and cache write
+)
 
 // Params holds all data needed to build the statusline.
 type Params struct {
 	LoginType          string
 	Model              string
+	ModelMaxLen        int      // 0 means no limit
 	ContextUsedPct     *float64 // nil when unavailable
 	ContextWindowSize  int      // context_window.context_window_size from stdin
 	CompactWindow      string   // raw CLAUDE_CODE_AUTO_COMPACT_WINDOW value
 	CompactPctOverride string   // raw CLAUDE_AUTOCOMPACT_PCT_OVERRIDE value
 	Exceeds200kTokens  bool
+	CompactTurns       *int   // estimated turns until auto-compaction; nil when unknown
+	ContextTokens      string // "", TokensTotal or TokensDetail
 	Usage              *usage.Response
 	StdinRateLimits    *struct {
 		FiveHour *stdin.RateLimit `json:"five_hour"`
 		SevenDay *stdin.RateLimit `json:"seven_day"`
 	}
-	SubscriptionType string // raw subscription type for peak hours check
-	Status           *status.Response
-	Update           *update.Response
-	ShowCwd          bool
-	Cwd              string // raw working directory path
-	CwdMaxLen        int
-	ShowBranch       bool
-	Branch           string // current git branch name
-	BranchMaxLen     int
-	CacheMiss        bool
-	ShowCost         bool
-	CostUSD          float64
+	FiveHourExhausted time.Time // projected 5-hour quota exhaustion; zero when unknown
+	SubscriptionType  string    // raw subscription type for peak hours check
+	Status            *status.Response
+	Update            *update.Response
+	ShowCwd           bool
+	Cwd               string // raw working directory path
+	CwdURL            string // file:// link for the working directory; "" for none
+	CwdMaxLen         int
+	ShowBranch        bool
+	Branch            string      // current git branch name
+	BranchURL         string      // web link to the branch on its forge; "" for none
+	DetachedHead      string      // tag name or "@" and short SHA when HEAD is detached
+	GitStatus         *git.Status // working tree state; nil when not collected
+	GitOperation      string      // operation in progress, e.g. "REBASE"
+	GitAhead          int         // commits ahead of the upstream branch
+	GitBehind         int         // commits behind the upstream branch
+	BranchMaxLen      int
+	CacheMiss         bool
+	CacheMisses       int // consecutive cache misses in the session; 0 when untracked
+	ShowCacheHit      bool
+	CacheHitPct       *int // session prompt cache hit ratio; nil when unknown
+	ShowCost          bool
+	CostUSD           float64
+	ShowProjectCost   bool
+	ProjectCostUSD    float64        // cumulative cost of the project across sessions
+	BudgetPct         int            // highest consumption of the cost budgets; 0 when none
+	Layout            []string       // segment names in order; nil means DefaultLayout
+	Lines             [][]string     // one layout per output line; overrides Layout when set
+	Format            string         // text/template format; overrides Layout when set
+	MaxWidth          int            // display cells available per line; 0 means unlimited
+	Priorities        map[string]int // per-segment drop priority overrides
+	Theme             *Theme         // nil means DefaultTheme
+	Stdin             stdin.Data
 }
 
 // Build assembles the complete statusline string from all collected data.
+// The result has one line per configured layout line, separated by "\n".
 func Build(p Params) string {
-	// Identity.
-	identity := Identity(p.LoginType, p.Model)
-
-	// Context bar.
-	contextPct := 0
-	if p.ContextUsedPct != nil {
-		contextPct = int(math.Round(*p.ContextUsedPct))
-	}
-	warnPct := contextWarnPct(p.CompactWindow, p.ContextWindowSize, p.CompactPctOverride)
-	contextBar := Bar(contextPct, ContextColorFunc(warnPct))
-	if contextPct >= warnPct {
-		contextBar += " ⚠️"
-	}
-	if p.Exceeds200kTokens {
-		contextBar += " 🥵"
-	}
-	if p.CacheMiss {
-		contextBar += " 🥊"
-	}
-
-	// Usage bars.
-	// Aggregate 5h/7d bars come from stdin rate_limits (instant, no network).
-	// Per-model sub-bars and extra usage come from the usage API (when available).
-	var usage5h, usage7d, usageExtra string
-	now := time.Now()
-
-	// 5-hour bar from stdin.
-	if p.StdinRateLimits != nil && p.StdinRateLimits.FiveHour != nil &&
-		p.StdinRateLimits.FiveHour.UsedPercentage != nil {
-		pct5 := int(math.Round(*p.StdinRateLimits.FiveHour.UsedPercentage))
-		usage5h = Bar(pct5, QuotaColor)
-		if reset := ResetTimeUnix(p.StdinRateLimits.FiveHour.ResetsAt, now); reset != "" {
-			usage5h += " (" + reset + ")"
-		}
-		if policy.IsPeakHours(now, p.SubscriptionType) {
-			usage5h = "⚡️" + usage5h
+	s := newState(p, time.Now())
+	if p.Format != "" {
+		out, err := buildFormat(p.Format, s)
+		if err == nil {
+			return finishLines(strings.Split(strings.TrimRight(out, "\n"), "\n"), s.Theme)
 		}
+		log.Printf("render: %v (falling back to layout)", err)
 	}
 
-	// 7-day bar from stdin.
-	if p.StdinRateLimits != nil && p.StdinRateLimits.SevenDay != nil &&
-		p.StdinRateLimits.SevenDay.UsedPercentage != nil {
-		pct7 := int(math.Round(*p.StdinRateLimits.SevenDay.UsedPercentage))
-		usage7d = Bar(pct7, QuotaColor)
-		if reset := ResetTimeUnix(p.StdinRateLimits.SevenDay.ResetsAt, now); reset != "" {
-			usage7d += " (" + reset + ")"
+	lines := p.Lines
+	if len(lines) == 0 {
+		layout := p.Layout
+		if len(layout) == 0 {
+			layout = DefaultLayout
 		}
+		lines = [][]string{layout}
 	}
-
-	// Usage API: fall back for aggregate bars when stdin doesn't provide them,
-	// plus per-model sub-bars and extra usage.
-	if p.Usage != nil {
-		if usage5h == "" && p.Usage.FiveHour != nil {
-			pct5 := int(math.Round(p.Usage.FiveHour.Utilization))
-			usage5h = Bar(pct5, QuotaColor)
-			if reset := ResetTime(p.Usage.FiveHour.ResetsAt, now); reset != "" {
-				usage5h += " (" + reset + ")"
-			}
-			if policy.IsPeakHours(now, p.SubscriptionType) {
-				usage5h = "⚡️" + usage5h
-			}
-		}
-		if usage7d == "" && p.Usage.SevenDay != nil {
-			pct7 := int(math.Round(p.Usage.SevenDay.Utilization))
-			usage7d = Bar(pct7, QuotaColor)
-			if reset := ResetTime(p.Usage.SevenDay.ResetsAt, now); reset != "" {
-				usage7d += " (" + reset + ")"
-			}
-		}
-
-		// Sub-bars for 7d usage limits.
-		subSep := Dim + " · " + Reset
-		if usage7d == "" {
-			subSep = " " + Reset
-		}
-		for _, model := range []struct {
-			q     *usage.QuotaLimit
-			label string
-		}{
-			{p.Usage.SevenDaySonnet, "sonnet"},
-			{p.Usage.SevenDayOpus, "opus"},
-			{p.Usage.SevenDayCowork, "cowork"},
-			{p.Usage.SevenDayOAuthApp, "oauth"},
-		} {
-			if model.q != nil {
-				pct := int(math.Round(model.q.Utilization))
-				usage7d += subSep + QuotaSubBar(
-					pct, model.label, ResetTime(model.q.ResetsAt, now),
-				)
-			}
+	out := make([]string, 0, len(lines))
+	for _, layout := range lines {
+		if line := buildLine(layout, s); line != "" {
+			out = append(out, line)
 		}
+	}
+	return finishLines(out, s.Theme)
+}
 
-		if e := p.Usage.ExtraUsage; e != nil && e.IsEnabled && e.MonthlyLimit != nil && e.UsedCredits != nil {
-			usageExtra = ExtraUsage(int(*e.UsedCredits)/100, int(*e.MonthlyLimit)/100)
+// buildLine renders the segments of one layout line.
+func buildLine(layout []string, s *state) string {
+	pieces := make([]Piece, 0, len(layout))
+	for _, name := range layout {
+		seg, ok := segments[name]
+		if !ok {
+			continue
 		}
+		pieces = append(pieces, Piece{Name: name, Text: seg.render(s)})
 	}
-
-	// Service status.
-	var statusStr string
-	if p.Status != nil {
-		statusStr = StatusIndicator(p.Status.Status.Indicator)
+	if s.MaxWidth > 0 {
+		pieces = fit(pieces, s)
 	}
+	return s.Theme.Output(pieces)
+}
 
-	// Update indicator.
-	var updateStr string
-	if p.Update != nil {
-		updateStr = UpdateIndicator(p.Update.TagName)
-	}
+// shrinkStep shortens or drops one piece of a line that is too wide.
+type shrinkStep struct {
+	index    int
+	priority int
+	compact  bool
+}
 
-	// Working directory and git branch.
-	sep := Dim + " │ " + Reset
-	identityFull := identity
-	if p.ShowCwd {
-		if name := cwdName(p.Cwd, p.CwdMaxLen); name != "" {
-			identityFull += sep + Yellow + name + Reset
+// fit shortens and drops pieces, lowest priority first, until the line fits
+// in s.MaxWidth display cells. Pieces with priority 0 are never dropped, so
+// the line may still overflow.
+func fit(pieces []Piece, s *state) []Piece {
+	var steps []shrinkStep
+	for i, p := range pieces {
+		if p.Text == "" {
+			continue
 		}
-	}
-	if p.ShowBranch {
-		if name := compactName(p.Branch, p.BranchMaxLen); name != "" {
-			identityFull += sep + Magenta + name + Reset
+		seg := segments[p.Name]
+		if seg.compact != nil {
+			steps = append(steps, shrinkStep{index: i, priority: seg.compactPriority, compact: true})
+		}
+		priority := seg.priority
+		if override, ok := s.Priorities[p.Name]; ok {
+			priority = override
+		}
+		if priority > 0 {
+			steps = append(steps, shrinkStep{index: i, priority: priority})
 		}
 	}
+	// Lowest priority first; on ties, the rightmost piece goes first.
+	slices.SortStableFunc(steps, func(a, b shrinkStep) int {
+		if a.priority != b.priority {
+			return a.priority - b.priority
+		}
+		return b.index - a.index
+	})
 
-	var costStr string
-	if p.ShowCost && p.CostUSD > 0 {
-		costStr = Cost(p.CostUSD)
+	for _, step := range steps {
+		if display.Width(s.Theme.Output(pieces)) <= s.MaxWidth {
+			break
+		}
+		p := &pieces[step.index]
+		switch {
+		case p.Text == "":
+		case step.compact:
+			p.Text = segments[p.Name].compact(s)
+		default:
+			p.Text = ""
+		}
 	}
+	return pieces
+}
 
-	out := Output(identityFull, contextBar, usage5h, usage7d, costStr, usageExtra, statusStr, updateStr)
-	// Leading reset clears stale ANSI state from previous renders.
-	// Non-breaking spaces prevent the terminal from collapsing whitespace.
-	return Reset + strings.ReplaceAll(out, " ", "\u00A0")
+// finishLines prepares rendered lines for the terminal and joins them.
+// A leading reset on each line clears stale ANSI state from previous renders,
+// unless color is disabled. Non-breaking spaces prevent the terminal from
+// collapsing whitespace.
+func finishLines(lines []string, t *Theme) string {
+	reset := Reset
+	if t.Depth == DepthNone {
+		reset = ""
+	}
+	if len(lines) == 0 {
+		return reset
+	}
+	for i, line := range lines {
+		lines[i] = reset + strings.ReplaceAll(line, " ", "\u00A0")
+	}
+	return strings.Join(lines, "\n")
 }
 
-func contextWarnPct(compactWindow string, contextWindowSize int, compactPctOverride string) int {
+// ContextWarnPct returns the context percentage at which the compaction
+// warning shows, from the raw CLAUDE_CODE_AUTO_COMPACT_WINDOW and
+// CLAUDE_AUTOCOMPACT_PCT_OVERRIDE values and the context window size.
+func ContextWarnPct(compactWindow string, contextWindowSize int, compactPctOverride string) int {
 	pct := int(math.Round(
 		float64(compactWindowPct(compactWindow, contextWindowSize)) *
 			float64(warnPctOfCompactWindow(compactPctOverride)) / 100,
@@ -203,6 +216,17 @@ func contextWarnPct(compactWindow string, contextWindowSize int, compactPctOverr
 	return max(1, pct)
 }
 
+// CompactPct returns the context percentage at which Claude Code
+// auto-compacts, approximately: 95% of the effective context window, or the
+// CLAUDE_AUTOCOMPACT_PCT_OVERRIDE percentage of it.
+func CompactPct(compactWindow string, contextWindowSize int, compactPctOverride string) int {
+	pct := 95
+	if n, err := strconv.Atoi(compactPctOverride); err == nil && n > 0 && n <= 100 {
+		pct = n
+	}
+	return max(1, int(math.Round(float64(compactWindowPct(compactWindow, contextWindowSize))*float64(pct)/100)))
+}
+
 func compactWindowPct(compactWindow string, contextWindowSize int) int {
 	windowTokens, err := strconv.Atoi(compactWindow)
 	if err != nil || windowTokens <= 0 || contextWindowSize <= 0 {
@@ -228,17 +252,41 @@ func warnPctOfCompactWindow(compactPctOverride string) int {
 }
 
 // Bar renders a progress bar with ANSI colors.
-func Bar(pct int, colorFn func(int) string) string {
+func (t Theme) Bar(style BarStyle, pct int, colorFn func(int) string) string {
 	pct = max(0, min(100, pct))
-	filled := pct * barWidth / 100
-	empty := barWidth - filled
-	color := colorFn(pct)
+	width := max(1, style.Width)
+	g := t.barGlyphs(style)
+	steps := len(g.partial) + 1
+	units := pct * width * steps / 100
+
+	full, rem := units/steps, units%steps
+	filled := strings.Repeat(g.filled, full)
+	if rem > 0 {
+		filled += g.partial[rem-1]
+		full++
+	}
+	empty := strings.Repeat(g.empty, width-full)
+	if filled != "" {
+		filled = g.capLeft + filled
+	} else {
+		empty = g.capLeft + empty
+	}
+	if empty != "" {
+		empty += g.capRight
+	} else {
+		filled += g.capRight
+	}
 
+	color := colorFn(pct)
+	reset := Reset
+	if color == "" && t.Muted == "" {
+		reset = ""
+	}
 	return fmt.Sprintf(
-		"%s%s%s%s%s %d%%",
-		color, strings.Repeat("█", filled),
-		Dim, strings.Repeat("░", empty),
-		Reset, pct,
+		"%s%s%s%s%s%s%s %d%%",
+		t.Glyphs.BarLeft, color, filled,
+		t.Muted, empty,
+		reset, t.Glyphs.BarRight, pct,
 	)
 }
 
@@ -247,67 +295,87 @@ func Bar(pct int, colorFn func(int) string) string {
 //   - Dumb (yellow):  41–60% — quality starts to degrade
 //   - Danger (orange): 61%–warnPct — significant quality loss
 //   - Near compaction (red): ≥warnPct — approaching auto-compaction
-func ContextColorFunc(warnPct int) func(int) string {
+//
+// The colors and boundaries in parentheses are the defaults; the first two
+// boundaries come from ContextZones. The near-compaction zone always starts
+// at warnPct, even when it is below a configured boundary.
+func (t Theme) ContextColorFunc(warnPct int) func(int) string {
 	return func(pct int) string {
 		switch {
 		case pct >= warnPct:
-			return Red
-		case pct > 60:
-			return Orange
-		case pct > 40:
-			return Yellow
+			return t.ContextCompact
+		case pct > t.ContextZones[1]:
+			return t.ContextHot
+		case pct > t.ContextZones[0]:
+			return t.ContextWarn
 		default:
-			return Green
+			return t.ContextOK
 		}
 	}
 }
 
-// QuotaColor returns the ANSI color for a quota usage percentage.
-func QuotaColor(pct int) string {
+// QuotaColor returns the ANSI color for an aggregate quota usage percentage.
+func (t Theme) QuotaColor(pct int) string {
+	return t.quotaColor(pct, t.QuotaZones)
+}
+
+// ModelQuotaColor returns the ANSI color for a per-model quota usage
+// percentage.
+func (t Theme) ModelQuotaColor(pct int) string {
+	return t.quotaColor(pct, t.ModelZones)
+}
+
+func (t Theme) quotaColor(pct int, zones [2]int) string {
 	switch {
-	case pct >= 90:
-		return Red
-	case pct >= 75:
-		return BrightMagenta
+	case pct >= zones[1]:
+		return t.QuotaCritical
+	case pct >= zones[0]:
+		return t.QuotaWarn
 	default:
-		return BrightBlue
+		return t.QuotaOK
 	}
 }
 
 // Identity returns the "Login Type | Model" segment.
-func Identity(loginType, model string) string {
+func (t Theme) Identity(loginType, model string) string {
 	switch {
 	case model != "" && loginType != "":
-		return Cyan + loginType + Reset + Dim + " │ " + Reset + Cyan + model + Reset
+		return paint(t.Model, loginType) + paint(t.Muted, t.Glyphs.Separator) + paint(t.Model, model)
 	case model != "":
-		return Cyan + model + Reset
+		return paint(t.Model, model)
 	default:
 		return ""
 	}
 }
 
-// Output assembles all segments into a single-line status output.
-func Output(identity, contextBar, usage5h, usage7d, cost, usageExtra, statusIndicator, updateIndicator string) string {
-	sep := Dim + " │ " + Reset
+// Piece is a rendered segment, as passed to Output.
+type Piece struct {
+	Name string
+	Text string
+}
 
-	out := identity + sep + contextBar
-	if usage5h != "" {
-		out += sep + usage5h
-	}
-	if usage7d != "" {
-		out += sep + usage7d
-	}
-	if cost != "" {
-		out += sep + cost
-	}
-	if usageExtra != "" {
-		out += sep + usageExtra
-	}
-	if statusIndicator != "" {
-		out += sep + statusIndicator
-	}
-	if updateIndicator != "" {
-		out += sep + updateIndicator
+// Output assembles the rendered segments into a single-line status output,
+// in order. Empty segments are skipped. A segment that attaches to its
+// predecessor (e.g. per-model sub-bars following the 7-day bar) is joined
+// with a sub-separator instead of the segment separator.
+func (t Theme) Output(pieces []Piece) string {
+	sep := paint(t.Muted, t.Glyphs.Separator)
+	subSep := paint(t.Muted, t.Glyphs.SubSeparator)
+
+	var out, prev string
+	for _, p := range pieces {
+		if p.Text == "" {
+			continue
+		}
+		switch {
+		case prev == "":
+			out = p.Text
+		case segments[p.Name].attach != "" && segments[p.Name].attach == prev:
+			out += subSep + p.Text
+		default:
+			out += sep + p.Text
+		}
+		prev = p.Name
 	}
 	return out
 }
@@ -315,12 +383,12 @@ func Output(identity, contextBar, usage5h, usage7d, cost, usageExtra, statusIndi
 // UpdateIndicator returns a green arrow when a newer version is available.
 // The arrow is an OSC 8 hyperlink to the GitHub release page.
 // Returns "" when tag is empty.
-func UpdateIndicator(tag string) string {
+func (t Theme) UpdateIndicator(tag string) string {
 	if tag == "" {
 		return ""
 	}
 	url := "https://github.com/fredrikaverpil/claudeline/releases/tag/" + tag
-	return hyperlink(url, Green+"↑"+Reset)
+	return t.hyperlink(url, paint(t.Update, t.Glyphs.Update))
 }
 
 // ResetTime formats a reset timestamp, showing just the time if it's
@@ -333,13 +401,7 @@ func ResetTime(iso string, now time.Time) string {
 	if err != nil {
 		return ""
 	}
-	local := target.Local()
-	y1, m1, d1 := now.Local().Date()
-	y2, m2, d2 := local.Date()
-	if y1 == y2 && m1 == m2 && d1 == d2 {
-		return local.Format("15:04")
-	}
-	return local.Format("Mon 15:04")
+	return clock(target, now)
 }
 
 // ResetTimeUnix formats a Unix timestamp reset time, showing just the time if
@@ -348,7 +410,13 @@ func ResetTimeUnix(ts *float64, now time.Time) string {
 	if ts == nil {
 		return ""
 	}
-	local := time.Unix(int64(*ts), 0).Local()
+	return clock(time.Unix(int64(*ts), 0), now)
+}
+
+// clock formats t in local time, showing just the time if it's on the same
+// day as now, or the day and time otherwise.
+func clock(t, now time.Time) string {
+	local := t.Local()
 	y1, m1, d1 := now.Local().Date()
 	y2, m2, d2 := local.Date()
 	if y1 == y2 && m1 == m2 && d1 == d2 {
@@ -359,55 +427,128 @@ func ResetTimeUnix(ts *float64, now time.Time) string {
 
 // StatusIndicator returns a colored fire icon with severity bars for service disruptions.
 // Returns "" for "none", unknown indicators, or empty input.
-func StatusIndicator(indicator string) string {
+func (t Theme) StatusIndicator(indicator string) string {
 	const statusURL = "https://status.claude.com"
 
 	switch indicator {
 	case "minor":
-		return hyperlink(statusURL, Orange+"🔥▂"+Reset)
+		return t.hyperlink(statusURL, paint(t.Status, t.Glyphs.StatusMinor))
 	case "major":
-		return hyperlink(statusURL, Orange+"🔥▄▂"+Reset)
+		return t.hyperlink(statusURL, paint(t.Status, t.Glyphs.StatusMajor))
 	case "critical":
-		return hyperlink(statusURL, Orange+"🔥▆▄▂"+Reset)
+		return t.hyperlink(statusURL, paint(t.Status, t.Glyphs.StatusCritical))
 	default:
 		return ""
 	}
 }
 
-// hyperlink wraps text in an OSC 8 terminal hyperlink.
-func hyperlink(url, text string) string {
+// hyperlink wraps text in an OSC 8 terminal hyperlink to url. It returns
+// text alone when url is "" or color is disabled, so that a plain line has
+// no escape sequences at all.
+func (t Theme) hyperlink(url, text string) string {
+	if url == "" || t.Depth == DepthNone {
+		return text
+	}
 	return "\033]8;;" + url + "\a" + text + "\033]8;;\a"
 }
 
+// Tokens formats a token count with an SI suffix, e.g. 950, 1.2k, 84k or 1M.
+func Tokens(n int) string {
+	for _, u := range []struct {
+		size   int
+		suffix string
+	}{{1_000_000, "M"}, {1_000, "k"}} {
+		v := float64(n) / float64(u.size)
+		if v < 0.9995 { // 999_999 would otherwise read as 1000k
+			continue
+		}
+		if v < 10 && math.Round(v*10) != math.Round(v)*10 {
+			return strconv.FormatFloat(math.Round(v*10)/10, 'f', 1, 64) + u.suffix
+		}
+		return strconv.Itoa(int(math.Round(v))) + u.suffix
+	}
+	return strconv.Itoa(n)
+}
+
 // Cost formats a USD cost value for display (e.g. "$1.23").
 func Cost(usd float64) string {
 	return fmt.Sprintf("$%.2f", usd)
 }
 
+// Cache hit ratios below these percentages color the ratio as a warning
+// and as critical. A healthy session reads nearly all input from the cache.
+const (
+	cacheHitWarn     = 80
+	cacheHitCritical = 50
+)
+
+// CacheHit formats a prompt cache hit ratio, e.g. "⟳92%", colored by how
+// much of the input missed the cache.
+func (t Theme) CacheHit(pct int) string {
+	color := t.QuotaOK
+	switch {
+	case pct < cacheHitCritical:
+		color = t.QuotaCritical
+	case pct < cacheHitWarn:
+		color = t.QuotaWarn
+	}
+	return paint(color, fmt.Sprintf("%s%d%%", t.Glyphs.CacheHit, pct))
+}
+
+// Budget colors a cost by how much of a budget is used, in percent: the
+// quota warning color with a warning glyph from the first budget zone, and
+// the alert color with an over-budget glyph from the second.
+func (t Theme) Budget(cost string, pct int) string {
+	switch {
+	case pct >= t.BudgetZones[1]:
+		return paint(t.Alert, cost) + " " + t.Glyphs.BudgetOver
+	case pct >= t.BudgetZones[0]:
+		return paint(t.QuotaWarn, cost) + " " + t.Glyphs.BudgetWarn
+	}
+	return cost
+}
+
 // ExtraUsage returns the "$used/$limit" string for pay-as-you-go overage.
 // Returns "" when used is zero. Colors red when 80%+ of limit is used.
-func ExtraUsage(used, limit int) string {
+func (t Theme) ExtraUsage(used, limit int) string {
 	if used == 0 {
 		return ""
 	}
 	s := fmt.Sprintf("$%d/$%d", used, limit)
 	if limit > 0 && used*100/limit >= 80 {
-		return Red + s + Reset
+		return paint(t.Alert, s)
 	}
 	return s
 }
 
-// QuotaSubBar renders a per-model quota bar with a trailing label.
-func QuotaSubBar(pct int, label, resetTime string) string {
-	s := Bar(pct, QuotaColor) + " " + label
+// QuotaSubBar renders a per-model quota bar with a trailing label, pace and
+// reset time. A zero pace or empty reset time is omitted.
+func (t Theme) QuotaSubBar(pct int, label string, pace int, resetTime string) string {
+	s := t.Bar(t.QuotaBar, pct, t.ModelQuotaColor) + " " + label
+	if p := t.Pace(pace); p != "" {
+		s += " " + p
+	}
 	if resetTime != "" {
 		s += " (" + resetTime + ")"
 	}
 	return s
 }
 
+// Pace renders how many percentage points a quota is ahead of (+) or behind
+// (-) a linear pace through its window. Ahead of pace is in the critical
+// quota color, behind in the ok color. Returns "" for 0.
+func (t Theme) Pace(pace int) string {
+	switch {
+	case pace > 0:
+		return paint(t.QuotaCritical, fmt.Sprintf("+%d%%", pace))
+	case pace < 0:
+		return paint(t.QuotaOK, fmt.Sprintf("%d%%", pace))
+	}
+	return ""
+}
+
 // cwdName extracts the last path segment from cwd as the folder name.
-func cwdName(cwd string, maxLen int) string {
+func cwdName(cwd string, maxLen int, ellipsis string) string {
 	// Normalize separators for cross-platform support.
 	name := filepath.Base(strings.ReplaceAll(cwd, `\`, "/"))
 	switch {
@@ -417,15 +558,11 @@ func cwdName(cwd string, maxLen int) string {
 		// Bare Windows drive letter (e.g. "C:") — root of a drive.
 		return ""
 	}
-	return compactName(name, maxLen)
+	return compactName(name, maxLen, ellipsis)
 }
 
-// compactName truncates a name to maxLen runes using a Unicode ellipsis.
-func compactName(name string, maxLen int) string {
-	runes := []rune(name)
-	if len(runes) <= maxLen {
-		return name
-	}
-	half := (maxLen - 1) / 2
-	return string(runes[:half]) + "…" + string(runes[len(runes)-(maxLen-1-half):])
+// compactName truncates a name to maxLen terminal cells by replacing its
+// middle with ellipsis. A maxLen of 0 or less disables truncation.
+func compactName(name string, maxLen int, ellipsis string) string {
+	return display.TruncateMiddle(name, maxLen, ellipsis)
 }
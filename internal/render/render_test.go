@@ -5,12 +5,17 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/fredrikaverpil/claudeline/internal/display"
+	"github.com/fredrikaverpil/claudeline/internal/git"
+	"github.com/fredrikaverpil/claudeline/internal/stdin"
+	"github.com/fredrikaverpil/claudeline/internal/usage"
 )
 
 func TestContextColorFunc(t *testing.T) {
 	t.Parallel()
 
-	colorFn := ContextColorFunc(80)
+	colorFn := DefaultTheme.ContextColorFunc(80)
 
 	tests := []struct {
 		name string
@@ -208,16 +213,16 @@ func TestRenderOutput(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			got := Output(
-				tt.identity,
-				tt.contextBar,
-				tt.usage5h,
-				tt.usage7d,
-				tt.cost,
-				tt.usageExtra,
-				tt.statusIndicator,
-				tt.updateIndicator,
-			)
+			got := DefaultTheme.Output([]Piece{
+				{SegmentIdentity, tt.identity},
+				{SegmentContext, tt.contextBar},
+				{Segment5h, tt.usage5h},
+				{Segment7d, tt.usage7d},
+				{SegmentCost, tt.cost},
+				{SegmentExtra, tt.usageExtra},
+				{SegmentStatus, tt.statusIndicator},
+				{SegmentUpdate, tt.updateIndicator},
+			})
 			if got != tt.want {
 				t.Errorf("Output() =\n  %q\nwant\n  %q", got, tt.want)
 			}
@@ -225,6 +230,296 @@ func TestRenderOutput(t *testing.T) {
 	}
 }
 
+func TestOutput_subSeparator(t *testing.T) {
+	t.Parallel()
+
+	sep := Dim + " │ " + Reset
+	subSep := Dim + " · " + Reset
+
+	tests := []struct {
+		name   string
+		pieces []Piece
+		want   string
+	}{
+		{
+			name:   "models attach to 7d",
+			pieces: []Piece{{SegmentContext, "ctx"}, {Segment7d, "7d"}, {SegmentModels, "son"}},
+			want:   "ctx" + sep + "7d" + subSep + "son",
+		},
+		{
+			name:   "models without 7d",
+			pieces: []Piece{{SegmentContext, "ctx"}, {Segment7d, ""}, {SegmentModels, "son"}},
+			want:   "ctx" + sep + "son",
+		},
+		{
+			name:   "models not directly after 7d",
+			pieces: []Piece{{Segment7d, "7d"}, {SegmentCost, "$1.00"}, {SegmentModels, "son"}},
+			want:   "7d" + sep + "$1.00" + sep + "son",
+		},
+		{
+			name:   "empty leading segment",
+			pieces: []Piece{{SegmentIdentity, ""}, {SegmentContext, "ctx"}},
+			want:   "ctx",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := DefaultTheme.Output(tt.pieces)
+			if got != tt.want {
+				t.Errorf("Output() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuild_layout(t *testing.T) {
+	t.Parallel()
+
+	pct := 42.0
+	five := 9.0
+	p := Params{
+		LoginType:      "Pro",
+		Model:          "Opus",
+		ContextUsedPct: &pct,
+		StdinRateLimits: &struct {
+			FiveHour *stdin.RateLimit `json:"five_hour"`
+			SevenDay *stdin.RateLimit `json:"seven_day"`
+		}{FiveHour: &stdin.RateLimit{UsedPercentage: &five}},
+		Branch:   "feat/foo",
+		CostUSD:  1.5,
+		ShowCost: true,
+		Layout:   []string{SegmentModel, SegmentContext, SegmentBranch, Segment5h, SegmentCost},
+	}
+
+	// Branch is gated by ShowBranch.
+	got := strings.ReplaceAll(Build(p), "\u00A0", " ")
+	sep := Dim + " │ " + Reset
+	contextBar := DefaultTheme.Bar(DefaultBarStyle, 42, DefaultTheme.ContextColorFunc(80))
+	quotaBar := DefaultTheme.Bar(DefaultBarStyle, 9, DefaultTheme.QuotaColor)
+	want := Reset + Cyan + "Opus" + Reset + sep + contextBar + sep + quotaBar + sep + "$1.50"
+	if got != want {
+		t.Errorf("Build() =\n  %q\nwant\n  %q", got, want)
+	}
+
+	p.ShowBranch = true
+	p.BranchMaxLen = 30
+	got = strings.ReplaceAll(Build(p), "\u00A0", " ")
+	want = Reset + Cyan + "Opus" + Reset + sep + contextBar + sep +
+		Magenta + "feat/foo" + Reset + sep + quotaBar + sep + "$1.50"
+	if got != want {
+		t.Errorf("Build() =\n  %q\nwant\n  %q", got, want)
+	}
+
+	p.Model = "Opus 4.6 (1M context)"
+	p.ModelMaxLen = 9
+	p.Layout = []string{SegmentModel}
+	got = strings.ReplaceAll(Build(p), "\u00A0", " ")
+	want = Reset + Cyan + "Opus…ext)" + Reset
+	if got != want {
+		t.Errorf("Build() with ModelMaxLen =\n  %q\nwant\n  %q", got, want)
+	}
+}
+
+func TestBuild_lines(t *testing.T) {
+	t.Parallel()
+
+	pct := 42.0
+	p := Params{
+		LoginType:      "Pro",
+		Model:          "Opus",
+		ContextUsedPct: &pct,
+		ShowBranch:     true,
+		Branch:         "main",
+		BranchMaxLen:   30,
+		Lines: [][]string{
+			{SegmentIdentity, SegmentCwd, SegmentBranch},
+			{SegmentStatus, SegmentUpdate}, // empty line is dropped
+			{SegmentContext, Segment5h},
+		},
+	}
+
+	got := strings.Split(Build(p), "\n")
+	sep := Dim + " │ " + Reset
+	nbsp := func(s string) string { return strings.ReplaceAll(s, " ", "\u00A0") }
+	want := []string{
+		Reset + nbsp(DefaultTheme.Identity("Pro", "Opus")+sep+Magenta+"main"+Reset),
+		Reset + nbsp(DefaultTheme.Bar(DefaultBarStyle, 42, DefaultTheme.ContextColorFunc(80))),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Build() returned %d lines, want %d: %q", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d =\n  %q\nwant\n  %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestBuild_formatLines(t *testing.T) {
+	t.Parallel()
+
+	p := Params{Model: "Opus", Format: "{{.Model}} a\n{{.Context}}%\n"}
+	want := Reset + "Opus\u00A0a\n" + Reset + "0%"
+	if got := Build(p); got != want {
+		t.Errorf("Build() = %q, want %q", got, want)
+	}
+}
+
+func TestBuild_maxWidth(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	ctxPct := 42.0
+	// The 7-day quota resets in an hour and is on pace, so it shows no pace.
+	five, seven := 9.0, 99.0
+	reset := float64(now.Add(time.Hour).Unix())
+	base := Params{
+		LoginType:      "Pro",
+		Model:          "Opus",
+		ContextUsedPct: &ctxPct,
+		StdinRateLimits: &struct {
+			FiveHour *stdin.RateLimit `json:"five_hour"`
+			SevenDay *stdin.RateLimit `json:"seven_day"`
+		}{
+			FiveHour: &stdin.RateLimit{UsedPercentage: &five, ResetsAt: &reset},
+			SevenDay: &stdin.RateLimit{UsedPercentage: &seven, ResetsAt: &reset},
+		},
+		Usage: &usage.Response{
+			SevenDaySonnet: &usage.QuotaLimit{Utilization: 12},
+		},
+		ShowCwd:   true,
+		Cwd:       "/home/user/myproject",
+		CwdMaxLen: 30,
+	}
+	resetStr := " (" + ResetTimeUnix(&reset, now) + ")"
+
+	// Full line, for reference:
+	// Pro │ Opus │ myproject │ ██░░░ 42% │ ░░░░░ 9% (hh:mm) │ █████ 99% (hh:mm) · ░░░░░ 12% sonnet
+	tests := []struct {
+		name       string
+		maxWidth   int
+		priorities map[string]int
+		want       []string // segment texts expected in the output, stripped of ANSI
+		dropped    []string // substrings expected to be gone
+		fits       bool     // output must fit in maxWidth
+	}{
+		{
+			name:     "unlimited",
+			maxWidth: 0,
+			want:     []string{"Pro", "myproject", "9%" + resetStr, "12% sonnet"},
+		},
+		{
+			name:     "drops sub-bars first",
+			maxWidth: 75,
+			fits:     true,
+			want:     []string{"myproject", "9%" + resetStr, "99%" + resetStr},
+			dropped:  []string{"sonnet"},
+		},
+		{
+			name:     "then reset times",
+			maxWidth: 60,
+			fits:     true,
+			want:     []string{"Pro", "myproject", "9% │", "99%"},
+			dropped:  []string{"sonnet", resetStr},
+		},
+		{
+			name:     "then cwd",
+			maxWidth: 50,
+			fits:     true,
+			want:     []string{"Pro", "9%", "99%"},
+			dropped:  []string{"sonnet", resetStr, "myproject"},
+		},
+		{
+			name:     "never drops model and context",
+			maxWidth: 5,
+			want:     []string{"Opus", "42%"},
+			dropped:  []string{"Pro", "9%", "99%", "myproject"},
+		},
+		{
+			name:       "priority override keeps cwd",
+			maxWidth:   50,
+			priorities: map[string]int{SegmentCwd: 0},
+			want:       []string{"myproject"},
+			dropped:    []string{"sonnet", resetStr},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := base
+			p.MaxWidth = tt.maxWidth
+			p.Priorities = tt.priorities
+			got := stripANSI(strings.ReplaceAll(Build(p), "\u00A0", " "))
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("Build() = %q, missing %q", got, w)
+				}
+			}
+			for _, d := range tt.dropped {
+				if strings.Contains(got, d) {
+					t.Errorf("Build() = %q, should not contain %q", got, d)
+				}
+			}
+			if tt.fits && display.Width(got) > tt.maxWidth {
+				t.Errorf("Build() width = %d, exceeds %d: %q", display.Width(got), tt.maxWidth, got)
+			}
+		})
+	}
+}
+
+// stripANSI removes SGR and OSC 8 sequences for content assertions.
+func stripANSI(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\033' {
+			out.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == ']' {
+			end := strings.IndexByte(s[i:], '\a')
+			i += end
+			continue
+		}
+		for i < len(s) && s[i] != 'm' {
+			i++
+		}
+	}
+	return out.String()
+}
+
+func TestValidateLayout(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		layout  []string
+		wantErr bool
+	}{
+		{name: "default", layout: DefaultLayout},
+		{name: "empty", layout: nil},
+		{name: "subset", layout: []string{SegmentContext, Segment5h}},
+		{name: "fine-grained segments", layout: []string{"model", "context", "branch", "5h", "cost"}},
+		{name: "unknown segment", layout: []string{"context", "weather"}, wantErr: true},
+		{name: "duplicate segment", layout: []string{"context", "context"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateLayout(tt.layout)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateLayout(%v) error = %v, wantErr %v", tt.layout, err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestFormatResetTime(t *testing.T) {
 	t.Parallel()
 
@@ -289,7 +584,7 @@ func TestStatusIndicator(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			got := StatusIndicator(tt.indicator)
+			got := DefaultTheme.StatusIndicator(tt.indicator)
 			if got != tt.want {
 				t.Errorf("StatusIndicator(%q) = %q, want %q", tt.indicator, got, tt.want)
 			}
@@ -318,7 +613,7 @@ func TestUpdateIndicator(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			got := UpdateIndicator(tt.tag)
+			got := DefaultTheme.UpdateIndicator(tt.tag)
 			if got != tt.want {
 				t.Errorf("UpdateIndicator(%q) = %q, want %q", tt.tag, got, tt.want)
 			}
@@ -345,7 +640,7 @@ func TestExtraUsage(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			got := ExtraUsage(tt.used, tt.limit)
+			got := DefaultTheme.ExtraUsage(tt.used, tt.limit)
 			if got != tt.want {
 				t.Errorf("ExtraUsage(%d, %d) = %q, want %q", tt.used, tt.limit, got, tt.want)
 			}
@@ -373,7 +668,7 @@ func TestQuotaSubBar(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			got := QuotaSubBar(tt.pct, tt.label, tt.resetTime)
+			got := DefaultTheme.QuotaSubBar(tt.pct, tt.label, 0, tt.resetTime)
 			if !strings.Contains(got, tt.wantPct) {
 				t.Errorf("QuotaSubBar() = %q, missing percentage %q", got, tt.wantPct)
 			}
@@ -387,6 +682,60 @@ func TestQuotaSubBar(t *testing.T) {
 	}
 }
 
+func TestPace(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		pct      int
+		resetsAt time.Time
+		want     int
+	}{
+		{name: "unknown reset", pct: 60, want: 0},
+		{name: "on pace", pct: 50, resetsAt: now.Add(84 * time.Hour), want: 0},
+		{name: "ahead on day 2", pct: 60, resetsAt: now.Add(5 * 24 * time.Hour), want: 31},
+		{name: "behind on day 6", pct: 60, resetsAt: now.Add(24 * time.Hour), want: -26},
+		{name: "reset in the past", pct: 22, resetsAt: now.Add(-time.Hour), want: 0},
+		{name: "reset now", pct: 22, resetsAt: now, want: 0},
+		{name: "reset beyond window", pct: 5, resetsAt: now.Add(8 * 24 * time.Hour), want: 0},
+		{name: "reset at window end", pct: 5, resetsAt: now.Add(sevenDays), want: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := pace(tt.pct, tt.resetsAt, now, sevenDays); got != tt.want {
+				t.Errorf("pace() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuild_pace(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	seven := 60.0
+	reset := float64(now.Add(5 * 24 * time.Hour).Unix())
+	resetRFC := now.Add(24 * time.Hour).Format(time.RFC3339)
+	p := Params{
+		StdinRateLimits: &struct {
+			FiveHour *stdin.RateLimit `json:"five_hour"`
+			SevenDay *stdin.RateLimit `json:"seven_day"`
+		}{SevenDay: &stdin.RateLimit{UsedPercentage: &seven, ResetsAt: &reset}},
+		Usage: &usage.Response{
+			SevenDaySonnet: &usage.QuotaLimit{Utilization: 60, ResetsAt: resetRFC},
+		},
+		Layout: []string{Segment7d, SegmentModels},
+	}
+	got := stripANSI(strings.ReplaceAll(Build(p), "\u00A0", " "))
+	for _, want := range []string{"60% +31% (", "60% sonnet -26% ("} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Build() = %q, missing %q", got, want)
+		}
+	}
+}
+
 func TestBar(t *testing.T) {
 	t.Parallel()
 
@@ -412,7 +761,7 @@ func TestBar(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			got := Bar(tt.pct, colorFn)
+			got := DefaultTheme.Bar(DefaultBarStyle, tt.pct, colorFn)
 			fullCount := strings.Count(got, "█")
 			emptyCount := strings.Count(got, "░")
 			if fullCount != tt.wantFull {
@@ -448,7 +797,7 @@ func TestQuotaColor(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			got := QuotaColor(tt.pct)
+			got := DefaultTheme.QuotaColor(tt.pct)
 			if got != tt.want {
 				t.Errorf("QuotaColor(%d) = %q, want %q", tt.pct, got, tt.want)
 			}
@@ -479,7 +828,7 @@ func TestIdentity(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			got := Identity(tt.loginType, tt.model)
+			got := DefaultTheme.Identity(tt.loginType, tt.model)
 			if got != tt.want {
 				t.Errorf("Identity(%q, %q) = %q, want %q", tt.loginType, tt.model, got, tt.want)
 			}
@@ -490,7 +839,7 @@ func TestIdentity(t *testing.T) {
 func TestContextColorFunc_custom_warnPct(t *testing.T) {
 	t.Parallel()
 
-	colorFn := ContextColorFunc(85)
+	colorFn := DefaultTheme.ContextColorFunc(85)
 
 	tests := []struct {
 		name string
@@ -513,6 +862,61 @@ func TestContextColorFunc_custom_warnPct(t *testing.T) {
 	}
 }
 
+func TestCompactPct(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name               string
+		compactWindow      string
+		contextWindowSize  int
+		compactPctOverride string
+		want               int
+	}{
+		{name: "default", contextWindowSize: 200000, want: 95},
+		{name: "override", contextWindowSize: 200000, compactPctOverride: "70", want: 70},
+		{name: "compact window", compactWindow: "400000", contextWindowSize: 1000000, want: 38},
+		{name: "invalid override", contextWindowSize: 200000, compactPctOverride: "150", want: 95},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := CompactPct(tt.compactWindow, tt.contextWindowSize, tt.compactPctOverride); got != tt.want {
+				t.Errorf("CompactPct() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuild_compactTurns(t *testing.T) {
+	t.Parallel()
+
+	one, six := 1, 6
+	tests := []struct {
+		name  string
+		pct   float64
+		turns *int
+		want  string
+	}{
+		{name: "with warning", pct: 85, turns: &six, want: "85% ⚠️ ~6 turns"},
+		{name: "singular", pct: 90, turns: &one, want: "90% ⚠️ ~1 turn"},
+		{name: "unknown", pct: 85, want: "85% ⚠️"},
+		{name: "below warning", pct: 50, turns: &six, want: "50%"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := stripANSI(strings.ReplaceAll(Build(Params{
+				ContextUsedPct: &tt.pct,
+				CompactTurns:   tt.turns,
+				Layout:         []string{SegmentContext},
+			}), "\u00A0", " "))
+			if !strings.HasSuffix(got, tt.want) {
+				t.Errorf("Build() = %q, want suffix %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestContextWarnPct(t *testing.T) {
 	t.Parallel()
 
@@ -584,9 +988,9 @@ func TestContextWarnPct(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			got := contextWarnPct(tt.compactWindow, tt.contextWindowSize, tt.compactPctOverride)
+			got := ContextWarnPct(tt.compactWindow, tt.contextWindowSize, tt.compactPctOverride)
 			if got != tt.want {
-				t.Errorf("contextWarnPct(%q, %d, %q) = %d, want %d",
+				t.Errorf("ContextWarnPct(%q, %d, %q) = %d, want %d",
 					tt.compactWindow,
 					tt.contextWindowSize,
 					tt.compactPctOverride,
@@ -624,6 +1028,77 @@ func TestCost(t *testing.T) {
 	}
 }
 
+func TestTokens(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{n: 0, want: "0"},
+		{n: 999, want: "999"},
+		{n: 1000, want: "1k"},
+		{n: 1240, want: "1.2k"},
+		{n: 9960, want: "10k"},
+		{n: 84_400, want: "84k"},
+		{n: 200_000, want: "200k"},
+		{n: 999_999, want: "1M"},
+		{n: 1_500_000, want: "1.5M"},
+	}
+	for _, tt := range tests {
+		if got := Tokens(tt.n); got != tt.want {
+			t.Errorf("Tokens(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestBuild_contextTokens(t *testing.T) {
+	t.Parallel()
+
+	withUsage, err := stdin.Parse([]byte(`{"context_window":{"context_window_size":200000,"used_percentage":42,` +
+		`"total_input_tokens":70283,"total_output_tokens":55110,` +
+		`"current_usage":{"input_tokens":3,"output_tokens":173,` +
+		`"cache_creation_input_tokens":1240,"cache_read_input_tokens":82757}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pctOnly, err := stdin.Parse([]byte(`{"context_window":{"context_window_size":1000000,"used_percentage":12.4}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		mode     string
+		data     stdin.Data
+		maxWidth int
+		want     string
+	}{
+		{name: "off", data: withUsage, want: "42%"},
+		{name: "total", mode: TokensTotal, data: withUsage, want: "42% 84k/200k"},
+		{name: "detail", mode: TokensDetail, data: withUsage, want: "42% 84k/200k in:70k out:55k cr:83k cw:1.2k"},
+		{name: "detail dropped when narrow", mode: TokensDetail, data: withUsage, maxWidth: 20, want: "42% 84k/200k"},
+		{name: "from percent", mode: TokensDetail, data: pctOnly, want: "12% 124k/1M"},
+		{name: "unknown size", mode: TokensTotal, want: "0%"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := stripANSI(strings.ReplaceAll(Build(Params{
+				ContextUsedPct:    tt.data.ContextWindow.UsedPercentage,
+				ContextWindowSize: tt.data.ContextWindow.ContextWindowSize,
+				ContextTokens:     tt.mode,
+				MaxWidth:          tt.maxWidth,
+				Layout:            []string{SegmentContext},
+				Stdin:             tt.data,
+			}), "\u00A0", " "))
+			if !strings.HasSuffix(got, tt.want) {
+				t.Errorf("Build() = %q, want suffix %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestResetTime_invalid(t *testing.T) {
 	t.Parallel()
 
@@ -703,7 +1178,25 @@ func TestCompactName(t *testing.T) {
 			name:   "multibyte unicode",
 			input:  "日本語テスト文字列",
 			maxLen: 5,
-			want:   "日本…字列",
+			want:   "日…列",
+		},
+		{
+			name:   "wide character does not fit head",
+			input:  "a日本語テスト文字列",
+			maxLen: 6,
+			want:   "a…字列",
+		},
+		{
+			name:   "emoji zwj sequence kept whole",
+			input:  "👨\u200d👩\u200d👧-family-branch",
+			maxLen: 8,
+			want:   "👨\u200d👩\u200d👧-…anch",
+		},
+		{
+			name:   "combining accents kept with base",
+			input:  "ae\u0301e\u0301e\u0301b",
+			maxLen: 4,
+			want:   "a…e\u0301b",
 		},
 		{
 			name:   "maxLen 3",
@@ -717,12 +1210,73 @@ func TestCompactName(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			got := compactName(tt.input, tt.maxLen)
+			got := compactName(tt.input, tt.maxLen, "…")
 			if got != tt.want {
 				t.Errorf("compactName(%q, %d) = %q, want %q", tt.input, tt.maxLen, got, tt.want)
 			}
-			if len([]rune(got)) > tt.maxLen {
-				t.Errorf("compactName(%q, %d) rune length = %d, exceeds maxLen", tt.input, tt.maxLen, len([]rune(got)))
+			if w := display.Width(got); w > tt.maxLen {
+				t.Errorf("compactName(%q, %d) width = %d, exceeds maxLen", tt.input, tt.maxLen, w)
+			}
+		})
+	}
+}
+
+func TestBuild_projectCost(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		p    Params
+		want string
+	}{
+		{
+			name: "attached to session cost",
+			p:    Params{ShowCost: true, CostUSD: 1.2, ShowProjectCost: true, ProjectCostUSD: 42.1},
+			want: "$1.20 · Σ$42.10",
+		},
+		{
+			name: "without session cost",
+			p:    Params{ShowProjectCost: true, ProjectCostUSD: 42.1},
+			want: "Σ$42.10",
+		},
+		{
+			name: "hidden unless enabled",
+			p:    Params{ShowCost: true, CostUSD: 1.2, ProjectCostUSD: 42.1},
+			want: "$1.20",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p := tt.p
+			p.Layout = []string{SegmentCost, SegmentProjectCost}
+			if got := stripANSI(strings.ReplaceAll(Build(p), "\u00A0", " ")); got != tt.want {
+				t.Errorf("Build() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuild_budget(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		pct  int
+		want string
+	}{
+		{name: "no budget", pct: 0, want: "$1.20"},
+		{name: "within budget", pct: 50, want: "$1.20"},
+		{name: "nearly used up", pct: 80, want: BrightMagenta + "$1.20" + Reset + " 💸"},
+		{name: "over budget", pct: 130, want: Red + "$1.20" + Reset + " 🚨"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := Build(Params{ShowCost: true, CostUSD: 1.2, BudgetPct: tt.pct, Layout: []string{SegmentCost}})
+			got = strings.TrimPrefix(strings.ReplaceAll(got, "\u00A0", " "), Reset)
+			if got != tt.want {
+				t.Errorf("Build() = %q, want %q", got, tt.want)
 			}
 		})
 	}
@@ -759,6 +1313,207 @@ func TestBuild_CacheMiss(t *testing.T) {
 			t.Errorf("Build() with CacheMiss=false should not contain 🥊, got %q", got)
 		}
 	})
+
+	t.Run("repeated misses show count", func(t *testing.T) {
+		t.Parallel()
+		p := base
+		p.CacheMiss = true
+		p.CacheMisses = 3
+		if got := Build(p); !strings.Contains(got, "🥊×3") {
+			t.Errorf("Build() with CacheMisses=3 should contain 🥊×3, got %q", got)
+		}
+	})
+
+	t.Run("single miss has no count", func(t *testing.T) {
+		t.Parallel()
+		p := base
+		p.CacheMiss = true
+		p.CacheMisses = 1
+		if got := Build(p); strings.Contains(got, "×") {
+			t.Errorf("Build() with CacheMisses=1 should not contain a count, got %q", got)
+		}
+	})
+}
+
+func TestBuild_cacheHit(t *testing.T) {
+	t.Parallel()
+
+	pct := 25.0
+	tests := []struct {
+		name string
+		show bool
+		hit  int
+		want string
+	}{
+		{name: "hidden", hit: 92, want: ""},
+		{name: "healthy", show: true, hit: 92, want: BrightBlue + "⟳92%" + Reset},
+		{name: "warn", show: true, hit: 79, want: BrightMagenta + "⟳79%" + Reset},
+		{name: "critical", show: true, hit: 12, want: Red + "⟳12%" + Reset},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := Build(Params{
+				ContextUsedPct: &pct,
+				ShowCacheHit:   tt.show,
+				CacheHitPct:    &tt.hit,
+				Layout:         []string{SegmentContext, SegmentCache},
+			})
+			sub := Dim + " · " + Reset
+			if tt.want == "" {
+				if strings.Contains(got, "⟳") {
+					t.Errorf("Build() = %q, want no cache hit ratio", got)
+				}
+				return
+			}
+			if !strings.HasSuffix(strings.ReplaceAll(got, "\u00A0", " "), sub+tt.want) {
+				t.Errorf("Build() = %q, want suffix %q", got, sub+tt.want)
+			}
+		})
+	}
+}
+
+func TestBuild_gitState(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		branch   string
+		detached string
+		status   *git.Status
+		ahead    int
+		behind   int
+		op       string
+		want     string
+	}{
+		{name: "unknown", branch: "main", want: Magenta + "main" + Reset},
+		{name: "clean", branch: "main", status: &git.Status{}, want: Magenta + "main" + Reset},
+		{
+			name:   "all markers",
+			branch: "main",
+			status: &git.Status{Modified: true, Staged: true, Untracked: true, Conflicts: true},
+			want:   Magenta + "main" + Reset + " " + Magenta + "*+?!" + Reset,
+		},
+		{
+			name:   "operation",
+			branch: "main",
+			status: &git.Status{Conflicts: true},
+			op:     "REBASE",
+			want:   Magenta + "main" + Reset + " " + Magenta + "!" + Reset + " " + Red + "REBASE" + Reset,
+		},
+		{name: "operation without branch", op: "BISECT", want: Red + "BISECT" + Reset},
+		{
+			name:   "ahead and behind",
+			branch: "main",
+			status: &git.Status{Modified: true},
+			ahead:  3,
+			behind: 1,
+			want:   Magenta + "main" + Reset + " " + Magenta + "↑3" + Reset + " " + Magenta + "↓1" + Reset + " " + Magenta + "*" + Reset,
+		},
+		{name: "ahead only", branch: "main", ahead: 2, want: Magenta + "main" + Reset + " " + Magenta + "↑2" + Reset},
+		{name: "detached", detached: "@a1b2c3d", want: Orange + "@a1b2c3d" + Reset},
+		{name: "detached on tag", detached: "v1.2.0", op: "BISECT", want: Orange + "v1.2.0" + Reset + " " + Red + "BISECT" + Reset},
+		{name: "branch wins", branch: "main", detached: "@a1b2c3d", want: Magenta + "main" + Reset},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := Build(Params{
+				ShowBranch:   true,
+				Branch:       tt.branch,
+				DetachedHead: tt.detached,
+				BranchMaxLen: 30,
+				GitStatus:    tt.status,
+				GitOperation: tt.op,
+				GitAhead:     tt.ahead,
+				GitBehind:    tt.behind,
+				Layout:       []string{SegmentBranch},
+			})
+			if got = strings.ReplaceAll(got, "\u00A0", " "); got != Reset+tt.want {
+				t.Errorf("Build() = %q, want %q", got, Reset+tt.want)
+			}
+		})
+	}
+}
+
+func TestBuild_links(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		params Params
+		want   string
+	}{
+		{
+			name:   "branch",
+			params: Params{ShowBranch: true, Branch: "main", BranchURL: "https://github.com/o/r/tree/main", Layout: []string{SegmentBranch}},
+			want:   "\033]8;;https://github.com/o/r/tree/main\a" + Magenta + "main" + Reset + "\033]8;;\a",
+		},
+		{
+			name:   "branch without url",
+			params: Params{ShowBranch: true, Branch: "main", Layout: []string{SegmentBranch}},
+			want:   Magenta + "main" + Reset,
+		},
+		{
+			name:   "detached head is not linked",
+			params: Params{ShowBranch: true, DetachedHead: "@a1b2c3d", BranchURL: "https://github.com/o/r/tree/main", Layout: []string{SegmentBranch}},
+			want:   Orange + "@a1b2c3d" + Reset,
+		},
+		{
+			name:   "cwd",
+			params: Params{ShowCwd: true, Cwd: "/home/user/proj", CwdURL: "file://host/home/user/proj", Layout: []string{SegmentCwd}},
+			want:   "\033]8;;file://host/home/user/proj\a" + Yellow + "proj" + Reset + "\033]8;;\a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tt.params.BranchMaxLen = 30
+			tt.params.CwdMaxLen = 30
+			if got := Build(tt.params); got != Reset+tt.want {
+				t.Errorf("Build() = %q, want %q", got, Reset+tt.want)
+			}
+		})
+	}
+}
+
+func TestBuild_projection(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	five := 60.0
+	reset := float64(now.Add(2 * time.Hour).Unix())
+	soon := now.Add(time.Hour)
+	tests := []struct {
+		name      string
+		exhausted time.Time
+		resetsAt  *float64
+		want      bool
+	}{
+		{name: "before reset", exhausted: soon, resetsAt: &reset, want: true},
+		{name: "after reset", exhausted: now.Add(3 * time.Hour), resetsAt: &reset},
+		{name: "unknown reset", exhausted: soon},
+		{name: "no projection", resetsAt: &reset},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := stripANSI(strings.ReplaceAll(Build(Params{
+				StdinRateLimits: &struct {
+					FiveHour *stdin.RateLimit `json:"five_hour"`
+					SevenDay *stdin.RateLimit `json:"seven_day"`
+				}{FiveHour: &stdin.RateLimit{UsedPercentage: &five, ResetsAt: tt.resetsAt}},
+				FiveHourExhausted: tt.exhausted,
+				Layout:            []string{Segment5h},
+			}), "\u00A0", " "))
+			if strings.Contains(got, "→") != tt.want {
+				t.Errorf("Build() = %q, want projection %v", got, tt.want)
+			}
+			if want := "→ " + clock(soon, now); tt.want && !strings.Contains(got, want) {
+				t.Errorf("Build() = %q, want %q", got, want)
+			}
+		})
+	}
 }
 
 func TestCwdName(t *testing.T) {
@@ -848,7 +1603,7 @@ func TestCwdName(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			got := cwdName(tt.cwd, tt.maxLen)
+			got := cwdName(tt.cwd, tt.maxLen, "…")
 			if got != tt.want {
 				t.Errorf("cwdName(%q, %d) = %q, want %q", tt.cwd, tt.maxLen, got, tt.want)
 			}
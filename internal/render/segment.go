@@ -0,0 +1,497 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fredrikaverpil/claudeline/internal/git"
+	"github.com/fredrikaverpil/claudeline/internal/policy"
+	"github.com/fredrikaverpil/claudeline/internal/stdin"
+	"github.com/fredrikaverpil/claudeline/internal/usage"
+)
+
+// Segment names accepted in a layout.
+const (
+	SegmentIdentity    = "identity" // login type and model
+	SegmentLogin       = "login"
+	SegmentModel       = "model"
+	SegmentCwd         = "cwd"
+	SegmentBranch      = "branch"
+	SegmentContext     = "context"
+	SegmentCache       = "cache" // session prompt cache hit ratio
+	Segment5h          = "5h"
+	Segment7d          = "7d"
+	SegmentModels      = "models" // per-model 7-day sub-bars
+	SegmentCost        = "cost"
+	SegmentProjectCost = "project_cost" // cumulative cost of the project
+	SegmentExtra       = "extra"
+	SegmentStatus      = "status"
+	SegmentUpdate      = "update"
+)
+
+// DefaultLayout is the segment order used when no layout is configured.
+var DefaultLayout = []string{
+	SegmentIdentity,
+	SegmentCwd,
+	SegmentBranch,
+	SegmentContext,
+	SegmentCache,
+	Segment5h,
+	Segment7d,
+	SegmentModels,
+	SegmentCost,
+	SegmentProjectCost,
+	SegmentExtra,
+	SegmentStatus,
+	SegmentUpdate,
+}
+
+// segment is an addressable piece of the status line.
+type segment struct {
+	// render returns the segment text, or "" when there is nothing to show.
+	render func(s *state) string
+	// attach names the segment this one joins with a sub-separator when it
+	// directly follows it.
+	attach string
+
+	// priority orders segments for dropping when the line is too wide:
+	// lower priorities are dropped first, 0 means never drop.
+	priority int
+	// compact optionally renders a shorter form, used before dropping.
+	// compactPriority orders it against the other shrink steps.
+	compact         func(s *state) string
+	compactPriority int
+}
+
+// segments is the registry of all segments that can appear in a layout.
+var segments = map[string]segment{
+	SegmentIdentity: {
+		render:          func(s *state) string { return s.Theme.Identity(s.LoginType, s.Model) },
+		compact:         modelSegment,
+		compactPriority: 65,
+	},
+	SegmentLogin:  {render: loginSegment, priority: 65},
+	SegmentModel:  {render: modelSegment},
+	SegmentCwd:    {render: cwdSegment, priority: 30},
+	SegmentBranch: {render: branchSegment, priority: 60},
+	SegmentContext: {
+		render:          contextSegment,
+		compact:         func(s *state) string { return contextBar(s, false) },
+		compactPriority: 15,
+	},
+	SegmentCache: {render: cacheSegment, attach: SegmentContext, priority: 35},
+	Segment5h: {
+		render:          fiveHourSegment,
+		priority:        80,
+		compact:         func(s *state) string { return peakHours(s, quotaBar(s, s.fiveHour, false)) },
+		compactPriority: 20,
+	},
+	Segment7d: {
+		render:          sevenDaySegment,
+		priority:        70,
+		compact:         func(s *state) string { return quotaBar(s, s.sevenDay, false) },
+		compactPriority: 20,
+	},
+	SegmentModels:      {render: modelsSegment, attach: Segment7d, priority: 10},
+	SegmentCost:        {render: costSegment, priority: 55},
+	SegmentProjectCost: {render: projectCostSegment, attach: SegmentCost, priority: 52},
+	SegmentExtra:       {render: extraSegment, priority: 45},
+	SegmentStatus:      {render: statusSegment, priority: 50},
+	SegmentUpdate:      {render: updateSegment, priority: 40},
+}
+
+// ValidateLayout reports unknown or duplicate segment names in layout.
+func ValidateLayout(layout []string) error {
+	var errs []error
+	seen := map[string]bool{}
+	for _, name := range layout {
+		_, known := segments[name]
+		switch {
+		case !known:
+			errs = append(errs, fmt.Errorf("unknown segment %q", name))
+		case seen[name]:
+			errs = append(errs, fmt.Errorf("duplicate segment %q", name))
+		}
+		seen[name] = true
+	}
+	return errors.Join(errs...)
+}
+
+// state is the input shared by all segments of one render.
+type state struct {
+	Params
+	now        time.Time
+	contextPct int
+	warnPct    int
+	fiveHour   *Quota // nil when unavailable
+	sevenDay   *Quota // nil when unavailable
+	models     []Model
+}
+
+// Quota is an aggregate quota's utilization and reset time.
+type Quota struct {
+	Pct      int
+	ResetsAt time.Time // zero when unknown
+	Reset    string    // ResetsAt formatted for display, "" when unknown
+	// Projected is when the quota runs out at the current burn rate, zero
+	// unless that is before ResetsAt. Only set for the 5-hour quota.
+	Projected time.Time
+	// Pace is Pct minus the utilization a linear pace through the window
+	// would have reached by now, in percentage points. Positive means ahead
+	// of pace. Only set for 7-day quotas with a known reset time.
+	Pace int
+}
+
+// sevenDays is the length of the 7-day quota window.
+const sevenDays = 7 * 24 * time.Hour
+
+// pace returns pct minus the percentage of window elapsed before resetsAt.
+// Returns 0 when resetsAt is unknown, or outside the window starting now, as
+// for stale data from before the last reset.
+func pace(pct int, resetsAt, now time.Time, window time.Duration) int {
+	left := resetsAt.Sub(now)
+	if resetsAt.IsZero() || left <= 0 || left > window {
+		return 0
+	}
+	elapsed := 1 - left.Seconds()/window.Seconds()
+	return pct - int(math.Round(elapsed*100))
+}
+
+func newState(p Params, now time.Time) *state {
+	contextPct := 0
+	if p.ContextUsedPct != nil {
+		contextPct = int(math.Round(*p.ContextUsedPct))
+	}
+	s := &state{
+		Params:     p,
+		now:        now,
+		contextPct: contextPct,
+		warnPct:    ContextWarnPct(p.CompactWindow, p.ContextWindowSize, p.CompactPctOverride),
+	}
+	if s.Theme == nil {
+		s.Theme = &DefaultTheme
+	}
+	s.Model = compactName(p.Model, p.ModelMaxLen, s.Theme.Glyphs.Ellipsis)
+	// Aggregate bars come from stdin rate_limits (instant, no network),
+	// falling back to the usage API.
+	if rl := p.StdinRateLimits; rl != nil {
+		s.fiveHour = stdinQuota(rl.FiveHour, now)
+		s.sevenDay = stdinQuota(rl.SevenDay, now)
+	}
+	if p.Usage != nil {
+		if s.fiveHour == nil {
+			s.fiveHour = usageQuota(p.Usage.FiveHour, now)
+		}
+		if s.sevenDay == nil {
+			s.sevenDay = usageQuota(p.Usage.SevenDay, now)
+		}
+		// Per-model sub-bars only come from the usage API.
+		for _, m := range []struct {
+			q     *usage.QuotaLimit
+			label string
+		}{
+			{p.Usage.SevenDaySonnet, "sonnet"},
+			{p.Usage.SevenDayOpus, "opus"},
+			{p.Usage.SevenDayCowork, "cowork"},
+			{p.Usage.SevenDayOAuthApp, "oauth"},
+		} {
+			if q := usageQuota(m.q, now); q != nil {
+				q.Pace = pace(q.Pct, q.ResetsAt, now, sevenDays)
+				s.models = append(s.models, Model{Label: m.label, Quota: *q})
+			}
+		}
+	}
+	if q := s.sevenDay; q != nil {
+		q.Pace = pace(q.Pct, q.ResetsAt, now, sevenDays)
+	}
+	if q := s.fiveHour; q != nil && !p.FiveHourExhausted.IsZero() && p.FiveHourExhausted.Before(q.ResetsAt) {
+		q.Projected = p.FiveHourExhausted
+	}
+	return s
+}
+
+func stdinQuota(rl *stdin.RateLimit, now time.Time) *Quota {
+	if rl == nil || rl.UsedPercentage == nil {
+		return nil
+	}
+	q := &Quota{
+		Pct:   int(math.Round(*rl.UsedPercentage)),
+		Reset: ResetTimeUnix(rl.ResetsAt, now),
+	}
+	if rl.ResetsAt != nil {
+		q.ResetsAt = time.Unix(int64(*rl.ResetsAt), 0)
+	}
+	return q
+}
+
+func usageQuota(ql *usage.QuotaLimit, now time.Time) *Quota {
+	if ql == nil {
+		return nil
+	}
+	q := &Quota{
+		Pct:   int(math.Round(ql.Utilization)),
+		Reset: ResetTime(ql.ResetsAt, now),
+	}
+	if t, err := time.Parse(time.RFC3339, ql.ResetsAt); err == nil {
+		q.ResetsAt = t
+	}
+	return q
+}
+
+// quotaBar renders a quota bar, optionally with its pace and reset time.
+func quotaBar(s *state, q *Quota, withReset bool) string {
+	if q == nil {
+		return ""
+	}
+	bar := s.Theme.Bar(s.Theme.QuotaBar, q.Pct, s.Theme.QuotaColor)
+	if !withReset {
+		return bar
+	}
+	if p := s.Theme.Pace(q.Pace); p != "" {
+		bar += " " + p
+	}
+	if q.Reset != "" {
+		bar += " (" + q.Reset + ")"
+	}
+	return bar
+}
+
+func loginSegment(s *state) string {
+	if s.LoginType == "" {
+		return ""
+	}
+	return paint(s.Theme.Model, s.LoginType)
+}
+
+func modelSegment(s *state) string {
+	if s.Model == "" {
+		return ""
+	}
+	return paint(s.Theme.Model, s.Model)
+}
+
+func cwdSegment(s *state) string {
+	if !s.ShowCwd {
+		return ""
+	}
+	if name := cwdName(s.Cwd, s.CwdMaxLen, s.Theme.Glyphs.Ellipsis); name != "" {
+		return s.Theme.hyperlink(s.CwdURL, paint(s.Theme.Cwd, name))
+	}
+	return ""
+}
+
+func branchSegment(s *state) string {
+	if !s.ShowBranch {
+		return ""
+	}
+	var parts []string
+	if name := compactName(s.Branch, s.BranchMaxLen, s.Theme.Glyphs.Ellipsis); name != "" {
+		parts = append(parts, s.Theme.hyperlink(s.BranchURL, paint(s.Theme.Branch, name)))
+	} else if name := compactName(s.DetachedHead, s.BranchMaxLen, s.Theme.Glyphs.Ellipsis); name != "" {
+		parts = append(parts, paint(s.Theme.Detached, name))
+	}
+	if s.GitAhead > 0 {
+		parts = append(parts, paint(s.Theme.Branch, s.Theme.Glyphs.Ahead+strconv.Itoa(s.GitAhead)))
+	}
+	if s.GitBehind > 0 {
+		parts = append(parts, paint(s.Theme.Branch, s.Theme.Glyphs.Behind+strconv.Itoa(s.GitBehind)))
+	}
+	if m := gitMarkers(s.GitStatus); m != "" {
+		parts = append(parts, paint(s.Theme.Branch, m))
+	}
+	if s.GitOperation != "" {
+		parts = append(parts, paint(s.Theme.Alert, s.GitOperation))
+	}
+	return strings.Join(parts, " ")
+}
+
+// gitMarkers returns the working tree state markers: * modified, + staged,
+// ? untracked and ! conflicts. Returns "" for a clean or unknown state.
+func gitMarkers(st *git.Status) string {
+	if st == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, m := range []struct {
+		set    bool
+		marker byte
+	}{
+		{st.Modified, '*'},
+		{st.Staged, '+'},
+		{st.Untracked, '?'},
+		{st.Conflicts, '!'},
+	} {
+		if m.set {
+			b.WriteByte(m.marker)
+		}
+	}
+	return b.String()
+}
+
+func contextSegment(s *state) string {
+	return contextBar(s, s.ContextTokens == TokensDetail)
+}
+
+// contextBar renders the context bar with its token counts and indicators,
+// optionally with the token breakdown.
+func contextBar(s *state, detail bool) string {
+	g := s.Theme.Glyphs
+	bar := s.Theme.Bar(s.Theme.ContextBar, s.contextPct, s.Theme.ContextColorFunc(s.warnPct))
+	if s.ContextTokens != "" {
+		if t := contextTokens(s.Stdin); t != "" {
+			bar += " " + t
+		}
+		if d := tokenDetail(s.Stdin); detail && d != "" {
+			bar += " " + paint(s.Theme.Muted, d)
+		}
+	}
+	if s.contextPct >= s.warnPct {
+		bar += " " + g.Compact
+		if s.CompactTurns != nil {
+			bar += " " + turns(*s.CompactTurns)
+		}
+	}
+	if s.Exceeds200kTokens {
+		bar += " " + g.Extended
+	}
+	if s.CacheMiss {
+		bar += " " + g.CacheMiss
+		if s.CacheMisses > 1 {
+			bar += g.Times + strconv.Itoa(s.CacheMisses)
+		}
+	}
+	return bar
+}
+
+// cacheSegment renders the session's prompt cache hit ratio.
+func cacheSegment(s *state) string {
+	if !s.ShowCacheHit || s.CacheHitPct == nil {
+		return ""
+	}
+	return s.Theme.CacheHit(*s.CacheHitPct)
+}
+
+// contextTokens formats the tokens in the context window against its size,
+// e.g. "84k/200k". Returns "" when the size is unknown.
+func contextTokens(d stdin.Data) string {
+	cw := d.ContextWindow
+	if cw.ContextWindowSize <= 0 {
+		return ""
+	}
+	var used int
+	switch {
+	case cw.CurrentUsage != nil:
+		u := cw.CurrentUsage
+		used = u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+	case cw.UsedPercentage != nil:
+		used = int(math.Round(*cw.UsedPercentage * float64(cw.ContextWindowSize) / 100))
+	default:
+		return ""
+	}
+	return Tokens(used) + "/" + Tokens(cw.ContextWindowSize)
+}
+
+// tokenDetail formats the token breakdown: the session's total input and
+// output tokens, then the last turn's cache read and cache write, which are
+// only reported per turn. Returns "" when unavailable.
+func tokenDetail(d stdin.Data) string {
+	cw := d.ContextWindow
+	var parts []string
+	if cw.TotalInputTokens > 0 || cw.TotalOutputTokens > 0 {
+		parts = append(parts, "in:"+Tokens(cw.TotalInputTokens), "out:"+Tokens(cw.TotalOutputTokens))
+	}
+	if u := cw.CurrentUsage; u != nil {
+		parts = append(parts, "cr:"+Tokens(u.CacheReadInputTokens), "cw:"+Tokens(u.CacheCreationInputTokens))
+	}
+	return strings.Join(parts, " ")
+}
+
+// turns formats a turn count estimate, e.g. "~6 turns".
+func turns(n int) string {
+	if n == 1 {
+		return "~1 turn"
+	}
+	return fmt.Sprintf("~%d turns", n)
+}
+
+// fiveHourSegment renders the 5-hour quota bar, with the projected
+// exhaustion time when the quota would run out before it resets.
+func fiveHourSegment(s *state) string {
+	bar := quotaBar(s, s.fiveHour, true)
+	if bar != "" && !s.fiveHour.Projected.IsZero() {
+		bar += " " + paint(s.Theme.QuotaWarn, s.Theme.Glyphs.Projected+" "+clock(s.fiveHour.Projected, s.now))
+	}
+	return peakHours(s, bar)
+}
+
+// peakHours prefixes a non-empty 5-hour bar with the peak hours indicator.
+func peakHours(s *state, usage5h string) string {
+	if usage5h != "" && policy.IsPeakHours(s.now, s.SubscriptionType) {
+		return s.Theme.Glyphs.PeakHours + usage5h
+	}
+	return usage5h
+}
+
+// sevenDaySegment renders the aggregate 7-day quota bar.
+func sevenDaySegment(s *state) string {
+	return quotaBar(s, s.sevenDay, true)
+}
+
+// modelsSegment renders the per-model 7-day sub-bars.
+func modelsSegment(s *state) string {
+	var out string
+	for _, m := range s.models {
+		if out != "" {
+			out += paint(s.Theme.Muted, s.Theme.Glyphs.SubSeparator)
+		}
+		out += s.Theme.QuotaSubBar(m.Pct, m.Label, m.Pace, m.Reset)
+	}
+	return out
+}
+
+func costSegment(s *state) string {
+	if !s.ShowCost || s.CostUSD <= 0 {
+		return ""
+	}
+	if s.BudgetPct == 0 {
+		return Cost(s.CostUSD)
+	}
+	return s.Theme.Budget(Cost(s.CostUSD), s.BudgetPct)
+}
+
+// projectCostSegment renders the project's cumulative cost across sessions.
+func projectCostSegment(s *state) string {
+	if !s.ShowProjectCost || s.ProjectCostUSD <= 0 {
+		return ""
+	}
+	return s.Theme.Glyphs.ProjectCost + Cost(s.ProjectCostUSD)
+}
+
+func extraSegment(s *state) string {
+	if s.Usage == nil {
+		return ""
+	}
+	e := s.Usage.ExtraUsage
+	if e == nil || !e.IsEnabled || e.MonthlyLimit == nil || e.UsedCredits == nil {
+		return ""
+	}
+	return s.Theme.ExtraUsage(int(*e.UsedCredits)/100, int(*e.MonthlyLimit)/100)
+}
+
+func statusSegment(s *state) string {
+	if s.Status == nil {
+		return ""
+	}
+	return s.Theme.StatusIndicator(s.Status.Status.Indicator)
+}
+
+func updateSegment(s *state) string {
+	if s.Update == nil {
+		return ""
+	}
+	return s.Theme.UpdateIndicator(s.Update.TagName)
+}
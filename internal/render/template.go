@@ -0,0 +1,161 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/fredrikaverpil/claudeline/internal/policy"
+	"github.com/fredrikaverpil/claudeline/internal/status"
+	"github.com/fredrikaverpil/claudeline/internal/stdin"
+	"github.com/fredrikaverpil/claudeline/internal/update"
+	"github.com/fredrikaverpil/claudeline/internal/usage"
+)
+
+// TemplateData is the data available to a format template.
+//
+// The top-level fields are the values claudeline computes for its own
+// segments. Stdin, Usage, Status and Update expose the raw inputs for fields
+// claudeline does not render itself.
+type TemplateData struct {
+	Login        string  // plan or provider, e.g. "Pro" or "API"
+	Model        string  // model display name
+	Cwd          string  // working directory name, truncated
+	Branch       string  // git branch, truncated
+	Detached     string  // tag or "@" and short SHA of a detached HEAD, truncated
+	GitState     string  // working tree markers, e.g. "*+?"; "" when clean or off
+	GitOperation string  // operation in progress, e.g. "REBASE"
+	GitAhead     int     // commits ahead of the upstream branch
+	GitBehind    int     // commits behind the upstream branch
+	Context      int     // context window used, percent
+	WarnPct      int     // context percent at which the compaction warning shows
+	CompactTurns *int    // estimated turns until auto-compaction; nil when unknown
+	Tokens       string  // context tokens against the window size, e.g. "84k/200k"
+	Exceeds200k  bool    // session is in extended context territory
+	CacheMiss    bool    // last turn was a prompt cache miss
+	CacheMisses  int     // consecutive cache misses in the session; 0 when untracked
+	CacheHit     *int    // session prompt cache hit ratio in percent; nil when unknown
+	PeakHours    bool    // 5-hour quota burns faster than normal
+	FiveHour     *Quota  // nil when unavailable
+	SevenDay     *Quota  // nil when unavailable
+	Models       []Model // per-model 7-day quotas
+	Cost         float64 // session cost in USD
+	ProjectCost  float64 // cumulative project cost in USD
+	BudgetPct    int     // highest cost budget consumption in percent; 0 when none
+	Now          time.Time
+
+	Stdin  stdin.Data
+	Usage  *usage.Response  // nil when unavailable
+	Status *status.Response // nil when operational or unavailable
+	Update *update.Response // nil when up to date or unavailable
+}
+
+// Model is a per-model 7-day quota.
+type Model struct {
+	Label string // "sonnet", "opus", "cowork" or "oauth"
+	Quota
+}
+
+// ValidateFormat reports whether format is a valid template.
+func ValidateFormat(format string) error {
+	_, err := parseFormat(format, newState(Params{}, time.Now()))
+	return err
+}
+
+// buildFormat renders the status line from a format template.
+func buildFormat(format string, s *state) (string, error) {
+	tmpl, err := parseFormat(format, s)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, templateData(s)); err != nil {
+		return "", fmt.Errorf("execute format: %w", err)
+	}
+	return sb.String(), nil
+}
+
+func parseFormat(format string, s *state) (*template.Template, error) {
+	tmpl, err := template.New("format").Funcs(templateFuncs(s)).Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("parse format: %w", err)
+	}
+	return tmpl, nil
+}
+
+func templateFuncs(s *state) template.FuncMap {
+	return template.FuncMap{
+		// bar renders a context-colored progress bar.
+		"bar": func(pct int) string { return s.Theme.Bar(s.Theme.ContextBar, pct, s.Theme.ContextColorFunc(s.warnPct)) },
+		// quota renders a quota-colored progress bar.
+		"quota": func(pct int) string { return s.Theme.Bar(s.Theme.QuotaBar, pct, s.Theme.QuotaColor) },
+		// pace renders a 7-day quota's pace, e.g. "+12%", or "" when on pace.
+		"pace": s.Theme.Pace,
+		// segment renders a named layout segment.
+		"segment": func(name string) (string, error) {
+			seg, ok := segments[name]
+			if !ok {
+				return "", fmt.Errorf("unknown segment %q", name)
+			}
+			return seg.render(s), nil
+		},
+		// color wraps text in a theme role's color (e.g. "branch") or a
+		// color spec (e.g. "red", "208", "#ff8700").
+		"color": func(name, text string) (string, error) {
+			if field, ok := themeRoles[name]; ok {
+				return paint(*field(s.Theme), text), nil
+			}
+			c, err := parseColor(name)
+			if err != nil {
+				return "", err
+			}
+			return paint(c.sgr(s.Theme.Depth), text), nil
+		},
+		"dim":  func(text string) string { return paint(s.Theme.Muted, text) },
+		"cost": Cost,
+		// tokens formats a token count, e.g. "84k".
+		"tokens": Tokens,
+		// time formats a timestamp like the reset times on the quota bars.
+		"time": func(t time.Time) string {
+			if t.IsZero() {
+				return ""
+			}
+			return clock(t, s.now)
+		},
+	}
+}
+
+func templateData(s *state) TemplateData {
+	return TemplateData{
+		Login:        s.LoginType,
+		Model:        s.Model,
+		Cwd:          cwdName(s.Cwd, s.CwdMaxLen, s.Theme.Glyphs.Ellipsis),
+		Branch:       compactName(s.Branch, s.BranchMaxLen, s.Theme.Glyphs.Ellipsis),
+		Detached:     compactName(s.DetachedHead, s.BranchMaxLen, s.Theme.Glyphs.Ellipsis),
+		GitState:     gitMarkers(s.GitStatus),
+		GitOperation: s.GitOperation,
+		GitAhead:     s.GitAhead,
+		GitBehind:    s.GitBehind,
+		Context:      s.contextPct,
+		WarnPct:      s.warnPct,
+		CompactTurns: s.CompactTurns,
+		Tokens:       contextTokens(s.Stdin),
+		Exceeds200k:  s.Exceeds200kTokens,
+		CacheMiss:    s.CacheMiss,
+		CacheMisses:  s.CacheMisses,
+		CacheHit:     s.CacheHitPct,
+		PeakHours:    policy.IsPeakHours(s.now, s.SubscriptionType),
+		FiveHour:     s.fiveHour,
+		SevenDay:     s.sevenDay,
+		Cost:         s.CostUSD,
+		ProjectCost:  s.ProjectCostUSD,
+		BudgetPct:    s.BudgetPct,
+		Now:          s.now,
+		Stdin:        s.Stdin,
+		Usage:        s.Usage,
+		Status:       s.Status,
+		Update:       s.Update,
+		Models:       s.models,
+	}
+}
@@ -0,0 +1,124 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fredrikaverpil/claudeline/internal/stdin"
+	"github.com/fredrikaverpil/claudeline/internal/usage"
+)
+
+func TestBuild_format(t *testing.T) {
+	t.Parallel()
+
+	pct := 42.0
+	five := 9.0
+	reset := 1773050400.0 // 2026-03-09T10:00:00Z
+	base := Params{
+		LoginType:      "Pro",
+		Model:          "Opus",
+		ContextUsedPct: &pct,
+		StdinRateLimits: &struct {
+			FiveHour *stdin.RateLimit `json:"five_hour"`
+			SevenDay *stdin.RateLimit `json:"seven_day"`
+		}{FiveHour: &stdin.RateLimit{UsedPercentage: &five, ResetsAt: &reset}},
+		Usage: &usage.Response{
+			SevenDaySonnet: &usage.QuotaLimit{Utilization: 12},
+		},
+		Branch:       "feat/foo",
+		BranchMaxLen: 30,
+		CwdMaxLen:    30,
+		Stdin:        stdin.Data{Cwd: "/home/user/proj"},
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{
+			name:   "fields and bar",
+			format: "{{.Model}} {{bar .Context}} {{if .Branch}}on {{.Branch}}{{end}}",
+			want:   "Opus " + DefaultTheme.Bar(DefaultBarStyle, 42, DefaultTheme.ContextColorFunc(80)) + " on feat/foo",
+		},
+		{
+			name:   "quota and raw stdin",
+			format: "{{with .FiveHour}}{{quota .Pct}}{{end}} {{.Stdin.Cwd}}",
+			want:   DefaultTheme.Bar(DefaultBarStyle, 9, DefaultTheme.QuotaColor) + " /home/user/proj",
+		},
+		{
+			name:   "per-model quotas",
+			format: "{{range .Models}}{{.Label}}={{.Pct}}{{end}}",
+			want:   "sonnet=12",
+		},
+		{
+			name:   "segment and color",
+			format: `{{segment "identity"}} {{color "red" "!"}} {{dim "x"}}`,
+			want:   DefaultTheme.Identity("Pro", "Opus") + " " + Red + "!" + Reset + " " + Dim + "x" + Reset,
+		},
+		{
+			name:   "pace",
+			format: "{{pace 12}}{{pace 0}}{{pace -3}}",
+			want:   Red + "+12%" + Reset + BrightBlue + "-3%" + Reset,
+		},
+		{
+			name:   "computed warn percentage",
+			format: "{{.Context}}/{{.WarnPct}}",
+			want:   "42/80",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := base
+			p.Format = tt.format
+			got := strings.TrimPrefix(strings.ReplaceAll(Build(p), "\u00A0", " "), Reset)
+			if got != tt.want {
+				t.Errorf("Build() =\n  %q\nwant\n  %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuild_formatFallback(t *testing.T) {
+	t.Parallel()
+
+	pct := 42.0
+	p := Params{LoginType: "Pro", Model: "Opus", ContextUsedPct: &pct}
+	want := Build(p)
+
+	// Execution errors (unknown segment, nil dereference) fall back to the layout.
+	for _, format := range []string{`{{segment "weather"}}`, `{{.Usage.FiveHour.Utilization}}`} {
+		p.Format = format
+		if got := Build(p); got != want {
+			t.Errorf("Build() with format %q = %q, want layout fallback %q", format, got, want)
+		}
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		format  string
+		wantErr bool
+	}{
+		{name: "valid", format: "{{.Model}} {{bar .Context}}"},
+		{name: "unclosed action", format: "{{.Model", wantErr: true},
+		{name: "unknown function", format: "{{weather .Model}}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateFormat(tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateFormat(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package render
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Theme is a resolved color palette and the glyphs to draw with. Each color
+// field holds the ANSI sequence that starts a color, or "" for the terminal's
+// default foreground.
+type Theme struct {
+	// Context window zones, from plenty of room to near auto-compaction.
+	ContextOK      string
+	ContextWarn    string
+	ContextHot     string
+	ContextCompact string
+
+	// Quota zones.
+	QuotaOK       string
+	QuotaWarn     string
+	QuotaCritical string
+
+	Model    string // login type and model name
+	Cwd      string
+	Branch   string
+	Detached string // tag or commit of a detached HEAD, in place of the branch
+	Update   string // update available arrow
+	Status   string // service disruption indicator
+	Alert    string // extra usage near its limit
+	Muted    string // separators and empty bar cells
+
+	Depth      ColorDepth // color depth the theme was resolved for
+	Glyphs     Glyphs
+	ContextBar BarStyle
+	QuotaBar   BarStyle
+
+	// Zone boundaries in percent. Context zones are warn and hot, entered
+	// above each boundary; the near-compaction zone starts at the warning
+	// percentage on top of them. Quota zones are warn and critical, entered
+	// at each boundary. ModelZones apply to the per-model sub-bars.
+	// BudgetZones are the warn and over thresholds, in percent of a budget.
+	ContextZones [2]int
+	QuotaZones   [2]int
+	ModelZones   [2]int
+	BudgetZones  [2]int
+}
+
+// ThemeSpec maps theme roles (e.g. "context_ok", "branch") to color specs.
+// A color spec is a space-separated list of:
+//   - a color name: black, red, green, yellow, blue, magenta, cyan, white,
+//     their bright- variants (e.g. bright-blue), or orange
+//   - a 256-color palette index, e.g. 208
+//   - a hex RGB color, e.g. #ff8700
+//   - an attribute: bold, dim, italic, underline or reverse
+//   - none, for the default foreground
+//
+// In a custom theme, the "base" key names the theme to start from.
+type ThemeSpec map[string]string
+
+// themeRoles maps ThemeSpec keys to Theme fields.
+var themeRoles = map[string]func(t *Theme) *string{
+	"context_ok":      func(t *Theme) *string { return &t.ContextOK },
+	"context_warn":    func(t *Theme) *string { return &t.ContextWarn },
+	"context_hot":     func(t *Theme) *string { return &t.ContextHot },
+	"context_compact": func(t *Theme) *string { return &t.ContextCompact },
+	"quota_ok":        func(t *Theme) *string { return &t.QuotaOK },
+	"quota_warn":      func(t *Theme) *string { return &t.QuotaWarn },
+	"quota_critical":  func(t *Theme) *string { return &t.QuotaCritical },
+	"identity":        func(t *Theme) *string { return &t.Model },
+	"cwd":             func(t *Theme) *string { return &t.Cwd },
+	"branch":          func(t *Theme) *string { return &t.Branch },
+	"detached":        func(t *Theme) *string { return &t.Detached },
+	"update":          func(t *Theme) *string { return &t.Update },
+	"status":          func(t *Theme) *string { return &t.Status },
+	"alert":           func(t *Theme) *string { return &t.Alert },
+	"muted":           func(t *Theme) *string { return &t.Muted },
+}
+
+// Themes are the built-in palettes. Every built-in theme sets every role.
+var Themes = map[string]ThemeSpec{
+	"default": {
+		"context_ok":      "green",
+		"context_warn":    "yellow",
+		"context_hot":     "orange",
+		"context_compact": "red",
+		"quota_ok":        "bright-blue",
+		"quota_warn":      "bright-magenta",
+		"quota_critical":  "red",
+		"identity":        "cyan",
+		"cwd":             "yellow",
+		"branch":          "magenta",
+		"detached":        "orange",
+		"update":          "green",
+		"status":          "orange",
+		"alert":           "red",
+		"muted":           "dim",
+	},
+	// Darker shades that stay readable on light backgrounds.
+	"light": {
+		"context_ok":      "28",
+		"context_warn":    "136",
+		"context_hot":     "166",
+		"context_compact": "160",
+		"quota_ok":        "25",
+		"quota_warn":      "90",
+		"quota_critical":  "160",
+		"identity":        "30",
+		"cwd":             "94",
+		"branch":          "90",
+		"detached":        "130",
+		"update":          "28",
+		"status":          "166",
+		"alert":           "160",
+		"muted":           "245",
+	},
+	// https://ethanschoonover.com/solarized/
+	"solarized": {
+		"context_ok":      "#859900",
+		"context_warn":    "#b58900",
+		"context_hot":     "#cb4b16",
+		"context_compact": "#dc322f",
+		"quota_ok":        "#268bd2",
+		"quota_warn":      "#6c71c4",
+		"quota_critical":  "#dc322f",
+		"identity":        "#2aa198",
+		"cwd":             "#b58900",
+		"branch":          "#d33682",
+		"detached":        "#cb4b16",
+		"update":          "#859900",
+		"status":          "#cb4b16",
+		"alert":           "#dc322f",
+		"muted":           "#586e75",
+	},
+	// Okabe-Ito palette, distinguishable with the common forms of color
+	// blindness. Zones also step up in lightness contrast.
+	"okabe-ito": {
+		"context_ok":      "#56b4e9",
+		"context_warn":    "#f0e442",
+		"context_hot":     "#e69f00",
+		"context_compact": "bold #d55e00",
+		"quota_ok":        "#0072b2",
+		"quota_warn":      "#cc79a7",
+		"quota_critical":  "bold #d55e00",
+		"identity":        "#56b4e9",
+		"cwd":             "#f0e442",
+		"branch":          "#cc79a7",
+		"detached":        "#e69f00",
+		"update":          "#009e73",
+		"status":          "#e69f00",
+		"alert":           "#d55e00",
+		"muted":           "dim",
+	},
+	// No colors; the zones that need attention use attributes instead.
+	"monochrome": {
+		"context_ok":      "none",
+		"context_warn":    "none",
+		"context_hot":     "bold",
+		"context_compact": "bold reverse",
+		"quota_ok":        "none",
+		"quota_warn":      "bold",
+		"quota_critical":  "bold reverse",
+		"identity":        "none",
+		"cwd":             "none",
+		"branch":          "none",
+		"detached":        "italic",
+		"update":          "bold",
+		"status":          "bold",
+		"alert":           "bold",
+		"muted":           "dim",
+	},
+}
+
+// baseTheme holds the non-color defaults the built-in themes start from.
+var baseTheme = Theme{
+	Glyphs:     UnicodeGlyphs,
+	ContextBar: DefaultBarStyle,
+	QuotaBar:   DefaultBarStyle,
+
+	ContextZones: [2]int{40, 60},
+	QuotaZones:   [2]int{75, 90},
+	ModelZones:   [2]int{75, 90},
+	BudgetZones:  [2]int{80, 100},
+}
+
+// DefaultTheme is the palette used when no theme is configured, for a
+// 256-color terminal.
+var DefaultTheme = mustTheme(Themes["default"], Depth256)
+
+func mustTheme(spec ThemeSpec, depth ColorDepth) Theme {
+	t, err := spec.resolve(baseTheme, depth)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// LoadTheme resolves the named theme for a terminal with the given color
+// depth, looking in custom before the built-in themes. An empty name selects
+// the default theme. Colors are converted to the nearest color the terminal
+// supports; at DepthNone every role is empty.
+func LoadTheme(name string, custom map[string]map[string]string, depth ColorDepth) (Theme, error) {
+	return loadTheme(name, custom, depth, nil)
+}
+
+func loadTheme(name string, custom map[string]map[string]string, depth ColorDepth, seen []string) (Theme, error) {
+	if name == "" {
+		name = "default"
+	}
+	if slices.Contains(seen, name) {
+		return Theme{}, fmt.Errorf("theme %q: base cycle %s", name, strings.Join(append(seen, name), " -> "))
+	}
+	spec, ok := custom[name]
+	if !ok {
+		builtin, ok := Themes[name]
+		if !ok {
+			return Theme{}, fmt.Errorf("unknown theme %q (built-in: %s)",
+				name, strings.Join(slices.Sorted(maps.Keys(Themes)), ", "))
+		}
+		return builtin.resolve(baseTheme, depth)
+	}
+	base, err := loadTheme(spec["base"], custom, depth, append(seen, name))
+	if err != nil {
+		return Theme{}, err
+	}
+	t, err := ThemeSpec(spec).resolve(base, depth)
+	if err != nil {
+		return Theme{}, fmt.Errorf("theme %q: %w", name, err)
+	}
+	return t, nil
+}
+
+// resolve applies the color specs on top of base, rendered for depth.
+func (spec ThemeSpec) resolve(base Theme, depth ColorDepth) (Theme, error) {
+	t := base
+	t.Depth = depth
+	for _, role := range slices.Sorted(maps.Keys(spec)) {
+		if role == "base" {
+			continue
+		}
+		field, ok := themeRoles[role]
+		if !ok {
+			return Theme{}, fmt.Errorf("unknown role %q", role)
+		}
+		c, err := parseColor(spec[role])
+		if err != nil {
+			return Theme{}, fmt.Errorf("%s: %w", role, err)
+		}
+		*field(&t) = c.sgr(depth)
+	}
+	return t, nil
+}
@@ -0,0 +1,206 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestThemes_complete(t *testing.T) {
+	t.Parallel()
+
+	for name, spec := range Themes {
+		for role := range themeRoles {
+			if _, ok := spec[role]; !ok {
+				t.Errorf("theme %q does not set %q", name, role)
+			}
+		}
+		if _, err := LoadTheme(name, nil, Depth256); err != nil {
+			t.Errorf("LoadTheme(%q) error = %v", name, err)
+		}
+	}
+}
+
+func TestDefaultTheme(t *testing.T) {
+	t.Parallel()
+
+	// The default theme keeps the original colors.
+	want := Theme{
+		ContextOK:      Green,
+		ContextWarn:    Yellow,
+		ContextHot:     Orange,
+		ContextCompact: Red,
+		QuotaOK:        BrightBlue,
+		QuotaWarn:      BrightMagenta,
+		QuotaCritical:  Red,
+		Model:          Cyan,
+		Cwd:            Yellow,
+		Branch:         Magenta,
+		Detached:       Orange,
+		Update:         Green,
+		Status:         Orange,
+		Alert:          Red,
+		Muted:          Dim,
+		Depth:          Depth256,
+		Glyphs:         UnicodeGlyphs,
+		ContextBar:     DefaultBarStyle,
+		QuotaBar:       DefaultBarStyle,
+		ContextZones:   [2]int{40, 60},
+		QuotaZones:     [2]int{75, 90},
+		ModelZones:     [2]int{75, 90},
+		BudgetZones:    [2]int{80, 100},
+	}
+	if DefaultTheme != want {
+		t.Errorf("DefaultTheme = %+v, want %+v", DefaultTheme, want)
+	}
+}
+
+func TestLoadTheme(t *testing.T) {
+	t.Parallel()
+
+	light, err := LoadTheme("light", nil, Depth256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		theme   string
+		custom  map[string]map[string]string
+		check   func(Theme) bool
+		wantErr bool
+	}{
+		{
+			name:  "empty name is default",
+			check: func(th Theme) bool { return th == DefaultTheme },
+		},
+		{
+			name:  "custom inherits default",
+			theme: "mine",
+			custom: map[string]map[string]string{
+				"mine": {"branch": "#ff00ff"},
+			},
+			check: func(th Theme) bool {
+				return th.Branch == "\033[38;5;201m" && th.Cwd == DefaultTheme.Cwd
+			},
+		},
+		{
+			name:  "custom inherits base through another custom theme",
+			theme: "b",
+			custom: map[string]map[string]string{
+				"a": {"base": "light", "cwd": "bold blue"},
+				"b": {"base": "a", "branch": "none"},
+			},
+			check: func(th Theme) bool {
+				return th.Cwd == "\033[1;34m" && th.Branch == "" && th.QuotaOK == light.QuotaOK
+			},
+		},
+		{
+			name:  "custom overrides built-in name",
+			theme: "light",
+			custom: map[string]map[string]string{
+				"light": {"base": "default", "muted": "242"},
+			},
+			check: func(th Theme) bool { return th.Muted == "\033[38;5;242m" && th.Cwd == Yellow },
+		},
+		{name: "unknown theme", theme: "neon", wantErr: true},
+		{
+			name:    "unknown role",
+			theme:   "mine",
+			custom:  map[string]map[string]string{"mine": {"brnch": "red"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid color",
+			theme:   "mine",
+			custom:  map[string]map[string]string{"mine": {"branch": "#ff00"}},
+			wantErr: true,
+		},
+		{
+			name:  "base cycle",
+			theme: "a",
+			custom: map[string]map[string]string{
+				"a": {"base": "b"},
+				"b": {"base": "a"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := LoadTheme(tt.theme, tt.custom, Depth256)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadTheme(%q) error = %v, wantErr %v", tt.theme, err, tt.wantErr)
+			}
+			if tt.check != nil && !tt.check(got) {
+				t.Errorf("LoadTheme(%q) = %+v", tt.theme, got)
+			}
+		})
+	}
+}
+
+func TestBuild_theme(t *testing.T) {
+	t.Parallel()
+
+	mono, err := LoadTheme("monochrome", nil, Depth256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pct := 90.0
+	p := Params{
+		LoginType:      "Pro",
+		Model:          "Opus",
+		ContextUsedPct: &pct,
+		ShowBranch:     true,
+		Branch:         "main",
+		BranchMaxLen:   30,
+		Layout:         []string{SegmentIdentity, SegmentBranch, SegmentContext},
+		Theme:          &mono,
+	}
+	got := strings.ReplaceAll(Build(p), "\u00A0", " ")
+	sep := Dim + " │ " + Reset
+	want := Reset + "Pro" + sep + "Opus" + sep + "main" + sep +
+		"\033[1;7m████" + Dim + "░" + Reset + " 90% ⚠️"
+	if got != want {
+		t.Errorf("Build() =\n  %q\nwant\n  %q", got, want)
+	}
+}
+
+func TestTheme_zones(t *testing.T) {
+	t.Parallel()
+
+	theme := DefaultTheme
+	theme.ContextZones = [2]int{60, 85}
+	theme.QuotaZones = [2]int{50, 80}
+	theme.ModelZones = [2]int{90, 95}
+
+	context := theme.ContextColorFunc(80)
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "context below warn", got: context(60), want: Green},
+		{name: "context warn", got: context(61), want: Yellow},
+		// The compaction warning at 80% wins over the hot boundary at 85%.
+		{name: "context compaction before hot", got: context(80), want: Red},
+		{name: "quota ok", got: theme.QuotaColor(49), want: BrightBlue},
+		{name: "quota warn", got: theme.QuotaColor(50), want: BrightMagenta},
+		{name: "quota critical", got: theme.QuotaColor(80), want: Red},
+		{name: "model ok", got: theme.ModelQuotaColor(80), want: BrightBlue},
+		{name: "model warn", got: theme.ModelQuotaColor(90), want: BrightMagenta},
+		{name: "model critical", got: theme.ModelQuotaColor(95), want: Red},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// Sub-bars use the model zones, aggregate bars the quota zones.
+	if got, want := theme.QuotaSubBar(85, "opus", 0, ""), theme.Bar(theme.QuotaBar, 85, func(int) string { return BrightBlue })+" opus"; got != want {
+		t.Errorf("QuotaSubBar() = %q, want %q", got, want)
+	}
+}
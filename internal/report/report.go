@@ -0,0 +1,251 @@
+// Package report summarizes the usage history by day, week, session, project
+// or model.
+package report
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fredrikaverpil/claudeline/internal/history"
+)
+
+// Groupings are the supported values for the by argument of Build.
+var Groupings = []string{"day", "week", "session", "project", "model"}
+
+// Formats are the supported output formats of Write.
+var Formats = []string{"text", "csv", "json"}
+
+// Baseline is how far before the report start history should be read, so
+// that costs of sessions already running at the start are counted from their
+// last earlier sample rather than from zero.
+const Baseline = 24 * time.Hour
+
+// maxGap caps the time a single sample counts towards time above the
+// compaction warning, so that idle gaps between renders don't.
+const maxGap = 5 * time.Minute
+
+// Row summarizes the samples of one group.
+type Row struct {
+	Key          string        `json:"key"`
+	Sessions     int           `json:"sessions"`
+	CostUSD      float64       `json:"cost_usd"`
+	PeakFiveHour *float64      `json:"peak_five_hour_pct"` // nil when unknown
+	PeakSevenDay *float64      `json:"peak_seven_day_pct"` // nil when unknown
+	AboveWarn    time.Duration `json:"-"`
+	CacheMisses  int           `json:"cache_misses"`
+}
+
+// MarshalJSON adds the time above the compaction warning in seconds.
+func (r Row) MarshalJSON() ([]byte, error) {
+	type row Row
+	return json.Marshal(struct {
+		row
+		AboveWarnSeconds int64 `json:"above_warn_seconds"`
+	}{row(r), int64(r.AboveWarn / time.Second)})
+}
+
+// ParseSince parses a report start relative to now: a number of days or
+// weeks (e.g. "7d", "2w"), a Go duration (e.g. "12h") or a date
+// (2006-01-02, local time).
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, s, now.Location()); err == nil {
+		return t, nil
+	}
+	for suffix, unit := range map[string]int{"d": 1, "w": 7} {
+		if n, err := strconv.Atoi(strings.TrimSuffix(s, suffix)); err == nil && strings.HasSuffix(s, suffix) && n >= 0 {
+			return now.AddDate(0, 0, -n*unit), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid since %q (want e.g. 7d, 2w, 12h or 2006-01-02)", s)
+	}
+	return now.Add(-d), nil
+}
+
+// keyFunc returns the function that groups samples for by.
+func keyFunc(by string) (func(e history.Entry) string, error) {
+	switch by {
+	case "day":
+		return func(e history.Entry) string { return time.Unix(e.Time, 0).Format(time.DateOnly) }, nil
+	case "week":
+		return func(e history.Entry) string {
+			year, week := time.Unix(e.Time, 0).ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}, nil
+	case "session":
+		return func(e history.Entry) string { return e.SessionID }, nil
+	case "project":
+		return func(e history.Entry) string { return e.Project }, nil
+	case "model":
+		return func(e history.Entry) string { return e.Model }, nil
+	}
+	return nil, fmt.Errorf("invalid by %q (want %s)", by, strings.Join(Groupings, ", "))
+}
+
+// Build summarizes the samples at or after since, grouped by one of
+// Groupings. Earlier samples in entries only serve as cost baselines.
+// Chronological groupings are sorted oldest first, the others by cost.
+//
+// A session's cost is cumulative, so each sample adds the increase since the
+// session's previous sample. Time above the compaction warning is the time
+// from a sample past the warning to the session's next sample, at most 5
+// minutes per sample.
+func Build(entries []history.Entry, since time.Time, by string) ([]Row, error) {
+	key, err := keyFunc(by)
+	if err != nil {
+		return nil, err
+	}
+	entries = slices.SortedStableFunc(slices.Values(entries), func(a, b history.Entry) int {
+		return cmp.Compare(a.Time, b.Time)
+	})
+	rows := map[string]*Row{}
+	sessions := map[string]map[string]bool{}
+	prev := map[string]history.Entry{}
+	for _, e := range entries {
+		p, hasPrev := prev[e.SessionID]
+		prev[e.SessionID] = e
+		if e.Time < since.Unix() {
+			continue
+		}
+		k := key(e)
+		r := rows[k]
+		if r == nil {
+			r = &Row{Key: k}
+			rows[k] = r
+			sessions[k] = map[string]bool{}
+		}
+		sessions[k][e.SessionID] = true
+
+		switch {
+		case !hasPrev || e.CostUSD < p.CostUSD:
+			r.CostUSD += e.CostUSD // a new session, or its cost restarted
+		default:
+			r.CostUSD += e.CostUSD - p.CostUSD
+		}
+		r.PeakFiveHour = peak(r.PeakFiveHour, e.FiveHour)
+		r.PeakSevenDay = peak(r.PeakSevenDay, e.SevenDay)
+		if hasPrev && p.Warn {
+			start := max(p.Time, since.Unix())
+			r.AboveWarn += min(time.Duration(e.Time-start)*time.Second, maxGap)
+		}
+		if e.CacheMiss {
+			r.CacheMisses++
+		}
+	}
+
+	out := make([]Row, 0, len(rows))
+	for _, k := range slices.Sorted(maps.Keys(rows)) {
+		r := rows[k]
+		r.Sessions = len(sessions[k])
+		out = append(out, *r)
+	}
+	if by != "day" && by != "week" {
+		slices.SortStableFunc(out, func(a, b Row) int { return cmp.Compare(b.CostUSD, a.CostUSD) })
+	}
+	return out, nil
+}
+
+// Cost returns the cost incurred at or after since, counted like Build.
+func Cost(entries []history.Entry, since time.Time) float64 {
+	rows, _ := Build(entries, since, "session")
+	total := 0.0
+	for _, r := range rows {
+		total += r.CostUSD
+	}
+	return total
+}
+
+func peak(cur, v *float64) *float64 {
+	if v == nil || (cur != nil && *cur >= *v) {
+		return cur
+	}
+	return v
+}
+
+// Write writes rows grouped by by in one of Formats.
+func Write(w io.Writer, rows []Row, by, format string) error {
+	switch format {
+	case "text":
+		return writeText(w, rows, by)
+	case "csv":
+		return writeCSV(w, rows, by)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			By   string `json:"by"`
+			Rows []Row  `json:"rows"`
+		}{by, rows})
+	}
+	return fmt.Errorf("invalid format %q (want %s)", format, strings.Join(Formats, ", "))
+}
+
+func writeText(w io.Writer, rows []Row, by string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\tSESSIONS\tCOST\tPEAK 5H\tPEAK 7D\tABOVE WARN\tCACHE MISSES\n", strings.ToUpper(by))
+	var total Row
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%s\t%d\t$%.2f\t%s\t%s\t%s\t%d\n",
+			orNone(r.Key), r.Sessions, r.CostUSD, textPct(r.PeakFiveHour), textPct(r.PeakSevenDay),
+			duration(r.AboveWarn), r.CacheMisses)
+		total.CostUSD += r.CostUSD
+		total.AboveWarn += r.AboveWarn
+		total.CacheMisses += r.CacheMisses
+	}
+	fmt.Fprintf(tw, "TOTAL\t\t$%.2f\t\t\t%s\t%d\n", total.CostUSD, duration(total.AboveWarn), total.CacheMisses)
+	return tw.Flush()
+}
+
+func writeCSV(w io.Writer, rows []Row, by string) error {
+	cw := csv.NewWriter(w)
+	records := [][]string{{by, "sessions", "cost_usd", "peak_five_hour_pct", "peak_seven_day_pct", "above_warn_seconds", "cache_misses"}}
+	for _, r := range rows {
+		records = append(records, []string{
+			r.Key,
+			strconv.Itoa(r.Sessions),
+			strconv.FormatFloat(r.CostUSD, 'f', 2, 64),
+			csvPct(r.PeakFiveHour),
+			csvPct(r.PeakSevenDay),
+			strconv.FormatInt(int64(r.AboveWarn/time.Second), 10),
+			strconv.Itoa(r.CacheMisses),
+		})
+	}
+	return cw.WriteAll(records)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+func textPct(v *float64) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", *v)
+}
+
+func csvPct(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+// duration formats d as hours and minutes, e.g. "1h05m".
+func duration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -0,0 +1,188 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fredrikaverpil/claudeline/internal/history"
+)
+
+func pct(v float64) *float64 { return &v }
+
+// sample returns an entry minutes after 2026-10-14 09:00 local time.
+func sample(minutes int, session string, e history.Entry) history.Entry {
+	e.Time = time.Date(2026, 10, 14, 9, minutes, 0, 0, time.Local).Unix()
+	e.SessionID = session
+	return e
+}
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2026, 10, 14, 9, 0, 0, 0, time.Local)
+	entries := []history.Entry{
+		// Baseline before the report start: a is at $1.
+		sample(-10, "a", history.Entry{Project: "/p1", Model: "Opus", CostUSD: 1}),
+		sample(0, "a", history.Entry{Project: "/p1", Model: "Opus", CostUSD: 1.5, FiveHour: pct(20), Warn: true}),
+		sample(2, "a", history.Entry{Project: "/p1", Model: "Opus", CostUSD: 2, FiveHour: pct(35), CacheMiss: true}),
+		sample(1, "b", history.Entry{Project: "/p2", Model: "Sonnet", CostUSD: 0.5, SevenDay: pct(50), Warn: true}),
+		// 60 minutes after the warning, capped at 5.
+		sample(61, "b", history.Entry{Project: "/p2", Model: "Sonnet", CostUSD: 0.75, CacheMiss: true}),
+		// Same day, next day.
+		sample(24*60, "c", history.Entry{Project: "/p1", Model: "Opus", CostUSD: 3}),
+	}
+
+	tests := []struct {
+		by   string
+		want []Row
+	}{
+		{
+			by: "day",
+			want: []Row{
+				{Key: "2026-10-14", Sessions: 2, CostUSD: 1.75, PeakFiveHour: pct(35), PeakSevenDay: pct(50), AboveWarn: 7 * time.Minute, CacheMisses: 2},
+				{Key: "2026-10-15", Sessions: 1, CostUSD: 3},
+			},
+		},
+		{
+			by: "project",
+			want: []Row{
+				{Key: "/p1", Sessions: 2, CostUSD: 4, PeakFiveHour: pct(35), AboveWarn: 2 * time.Minute, CacheMisses: 1},
+				{Key: "/p2", Sessions: 1, CostUSD: 0.75, PeakSevenDay: pct(50), AboveWarn: 5 * time.Minute, CacheMisses: 1},
+			},
+		},
+		{
+			by: "session",
+			want: []Row{
+				{Key: "c", Sessions: 1, CostUSD: 3},
+				{Key: "a", Sessions: 1, CostUSD: 1, PeakFiveHour: pct(35), AboveWarn: 2 * time.Minute, CacheMisses: 1},
+				{Key: "b", Sessions: 1, CostUSD: 0.75, PeakSevenDay: pct(50), AboveWarn: 5 * time.Minute, CacheMisses: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			t.Parallel()
+			got, err := Build(entries, since, tt.by)
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(tt.want)
+			if !bytes.Equal(gotJSON, wantJSON) {
+				t.Errorf("Build() =\n  %s\nwant\n  %s", gotJSON, wantJSON)
+			}
+		})
+	}
+
+	if _, err := Build(entries, since, "hour"); err == nil {
+		t.Error("Build() with invalid by: error = nil, want error")
+	}
+}
+
+func TestCost(t *testing.T) {
+	t.Parallel()
+
+	entries := []history.Entry{
+		sample(0, "a", history.Entry{CostUSD: 1}),
+		sample(30, "a", history.Entry{CostUSD: 3}),
+		sample(40, "b", history.Entry{CostUSD: 0.5}),
+	}
+	since := time.Date(2026, 10, 14, 9, 15, 0, 0, time.Local)
+	if got, want := Cost(entries, since), 2.5; got != want {
+		t.Errorf("Cost() = %v, want %v", got, want)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "7d", want: now.AddDate(0, 0, -7)},
+		{in: "2w", want: now.AddDate(0, 0, -14)},
+		{in: "12h", want: now.Add(-12 * time.Hour)},
+		{in: "2026-10-01", want: time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local)},
+		{in: "yesterday", wantErr: true},
+		{in: "-3d", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseSince(tt.in, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSince(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseSince(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	rows := []Row{
+		{Key: "/p1", Sessions: 2, CostUSD: 4, PeakFiveHour: pct(35), AboveWarn: 65 * time.Minute, CacheMisses: 1},
+		{Key: "", Sessions: 1, CostUSD: 0.5},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "text",
+			want: "PROJECT  SESSIONS  COST   PEAK 5H  PEAK 7D  ABOVE WARN  CACHE MISSES\n" +
+				"/p1      2         $4.00  35%      -        1h05m       1\n" +
+				"(none)   1         $0.50  -        -        0h00m       0\n" +
+				"TOTAL              $4.50                    1h05m       1\n",
+		},
+		{
+			format: "csv",
+			want: "project,sessions,cost_usd,peak_five_hour_pct,peak_seven_day_pct,above_warn_seconds,cache_misses\n" +
+				"/p1,2,4.00,35,,3900,1\n" +
+				",1,0.50,,,0,0\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			if err := Write(&buf, rows, "project", tt.format); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Write() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		if err := Write(&buf, rows, "project", "json"); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		var got struct {
+			By   string           `json:"by"`
+			Rows []map[string]any `json:"rows"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.By != "project" || len(got.Rows) != 2 || got.Rows[0]["above_warn_seconds"] != 3900.0 {
+			t.Errorf("Write() = %s", buf.String())
+		}
+	})
+
+	if err := Write(&bytes.Buffer{}, rows, "project", "xml"); err == nil || !strings.Contains(err.Error(), "xml") {
+		t.Errorf("Write() with invalid format: error = %v, want error", err)
+	}
+}
@@ -0,0 +1,81 @@
+// Package sessionstore keeps a small record per Claude Code session in a
+// JSON file shared by all sessions, such as a session's context growth or
+// cache usage across turns.
+package sessionstore
+
+import (
+	"time"
+
+	"github.com/fredrikaverpil/claudeline/internal/filelock"
+	"github.com/fredrikaverpil/claudeline/internal/jsonfile"
+)
+
+// sessionTTL is how long an idle session is kept.
+const sessionTTL = 24 * time.Hour
+
+// store is the on-disk format.
+type store[T any] struct {
+	Sessions map[string]*entry[T] `json:"sessions"`
+}
+
+// entry is a session's record and when it last changed.
+type entry[T any] struct {
+	Seen   int64 `json:"seen"` // Unix seconds
+	Record T     `json:"record"`
+}
+
+// Update applies fn to the session's record in the file at path and returns
+// the record. fn gets the zero value and found false for a new session, and
+// reports whether it changed the record. A change is saved under a lock
+// file, so that concurrent sessions don't overwrite each other, and sessions
+// idle for a day are dropped. If the lock can't be taken, the changed record
+// is returned unsaved along with the error; renders repeat the same turn, so
+// a later render saves it.
+func Update[T any](path, session string, now time.Time, fn func(rec *T, found bool) bool) (T, error) {
+	unlock, lockErr := filelock.Lock(path)
+	if lockErr == nil {
+		defer unlock()
+	}
+	s := read[T](path)
+	rec, changed := s.apply(session, now, fn)
+	if !changed {
+		return rec, nil
+	}
+	if lockErr != nil {
+		return rec, lockErr
+	}
+	return rec, jsonfile.WriteAtomic(path, s)
+}
+
+// read returns the store at path, or an empty one when it can't be read.
+func read[T any](path string) *store[T] {
+	s, err := jsonfile.Read[store[T]](path)
+	if err != nil || s == nil {
+		s = &store[T]{}
+	}
+	if s.Sessions == nil {
+		s.Sessions = map[string]*entry[T]{}
+	}
+	return s
+}
+
+// apply runs fn on the session's record and, on a change, marks the session
+// seen and prunes idle sessions.
+func (s *store[T]) apply(session string, now time.Time, fn func(*T, bool) bool) (T, bool) {
+	e, found := s.Sessions[session]
+	if !found {
+		e = &entry[T]{}
+	}
+	if !fn(&e.Record, found) {
+		return e.Record, false
+	}
+	e.Seen = now.Unix()
+	s.Sessions[session] = e
+	cutoff := now.Add(-sessionTTL).Unix()
+	for id, e := range s.Sessions {
+		if e.Seen < cutoff {
+			delete(s.Sessions, id)
+		}
+	}
+	return e.Record, true
+}
@@ -0,0 +1,125 @@
+package sessionstore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fredrikaverpil/claudeline/internal/filelock"
+)
+
+// add returns an update function that adds n to a counter.
+func add(n int) func(*int, bool) bool {
+	return func(c *int, _ bool) bool {
+		*c += n
+		return n != 0
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "counts.json")
+
+	var got int
+	var found []bool
+	for _, n := range []int{1, 2, 3} {
+		var err error
+		got, err = Update(path, "a", now, func(c *int, ok bool) bool {
+			found = append(found, ok)
+			return add(n)(c, ok)
+		})
+		if err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+	}
+	if got != 6 {
+		t.Errorf("Update() = %d, want 6", got)
+	}
+	if want := []bool{false, true, true}; !slices.Equal(found, want) {
+		t.Errorf("found = %v, want %v", found, want)
+	}
+	if got, _ := Update(path, "b", now, add(10)); got != 10 {
+		t.Errorf("Update() for another session = %d, want 10", got)
+	}
+	if got, _ := Update(path, "a", now, add(0)); got != 6 {
+		t.Errorf("Update() without change = %d, want 6", got)
+	}
+}
+
+func TestUpdate_unchangedNotWritten(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "counts.json")
+	if _, err := Update(path, "a", time.Now(), add(0)); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat() error = %v, want the file not to be written", err)
+	}
+}
+
+func TestUpdate_prunes(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	s := read[int](filepath.Join(t.TempDir(), "missing.json"))
+	s.apply("old", now.Add(-25*time.Hour), add(1))
+	s.apply("new", now, add(1))
+	if _, ok := s.Sessions["old"]; ok {
+		t.Error("session idle for 25 hours was kept")
+	}
+	if _, ok := s.Sessions["new"]; !ok {
+		t.Error("active session was dropped")
+	}
+}
+
+func TestUpdate_locked(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "counts.json")
+	if _, err := Update(path, "a", time.Now(), add(1)); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := filelock.Lock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+	// The change is returned but not saved.
+	if got, err := Update(path, "a", time.Now(), add(1)); got != 2 || !errors.Is(err, filelock.ErrLocked) {
+		t.Errorf("Update() while locked = %d, %v, want 2, ErrLocked", got, err)
+	}
+	if got := read[int](path).Sessions["a"].Record; got != 1 {
+		t.Errorf("stored record = %d, want 1", got)
+	}
+}
+
+func TestUpdate_concurrent(t *testing.T) {
+	t.Parallel()
+
+	// Concurrent sessions each add to their own record; none may be lost.
+	path := filepath.Join(t.TempDir(), "counts.json")
+	sessions := []string{"a", "b", "c", "d"}
+	var wg sync.WaitGroup
+	for _, id := range sessions {
+		wg.Go(func() {
+			for range 5 {
+				if _, err := Update(path, id, time.Now(), add(1)); err != nil {
+					t.Errorf("Update(%q) error = %v", id, err)
+				}
+			}
+		})
+	}
+	wg.Wait()
+	for _, id := range sessions {
+		if got := read[int](path).Sessions[id].Record; got != 5 {
+			t.Errorf("session %q = %d, want 5", id, got)
+		}
+	}
+}
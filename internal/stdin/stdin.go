@@ -14,14 +14,22 @@ type RateLimit struct {
 // Data is the JSON structure received from Claude Code via stdin.
 // See Payload in stdin_test.go for the full schema.
 type Data struct {
-	Cwd   string `json:"cwd"`
-	Model struct {
+	SessionID string `json:"session_id"`
+	Cwd       string `json:"cwd"`
+	Model     struct {
 		DisplayName string `json:"display_name"`
 	} `json:"model"`
+	Workspace struct {
+		ProjectDir string `json:"project_dir"`
+	} `json:"workspace"`
 	ContextWindow struct {
 		ContextWindowSize int      `json:"context_window_size"`
 		UsedPercentage    *float64 `json:"used_percentage"`
+		TotalInputTokens  int      `json:"total_input_tokens"`  // session total
+		TotalOutputTokens int      `json:"total_output_tokens"` // session total
 		CurrentUsage      *struct {
+			InputTokens              int `json:"input_tokens"`
+			OutputTokens             int `json:"output_tokens"`
 			CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
 			CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 		} `json:"current_usage"`
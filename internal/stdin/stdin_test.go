@@ -184,7 +184,8 @@ func TestParse(t *testing.T) {
 		{
 			name: "valid with all fields",
 			input: `{"cwd":"/home/user","model":{"display_name":"Opus"},` +
-				`"context_window":{"context_window_size":1000000,"used_percentage":42.5}}`,
+				`"context_window":{"context_window_size":1000000,"used_percentage":42.5,` +
+				`"total_input_tokens":70283,"total_output_tokens":55110}}`,
 			want: Data{
 				Cwd: "/home/user",
 				Model: struct {
@@ -193,11 +194,15 @@ func TestParse(t *testing.T) {
 				ContextWindow: struct {
 					ContextWindowSize int      `json:"context_window_size"`
 					UsedPercentage    *float64 `json:"used_percentage"`
+					TotalInputTokens  int      `json:"total_input_tokens"`  // session total
+					TotalOutputTokens int      `json:"total_output_tokens"` // session total
 					CurrentUsage      *struct {
+						InputTokens              int `json:"input_tokens"`
+						OutputTokens             int `json:"output_tokens"`
 						CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
 						CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 					} `json:"current_usage"`
-				}{ContextWindowSize: 1000000, UsedPercentage: &pct},
+				}{ContextWindowSize: 1000000, UsedPercentage: &pct, TotalInputTokens: 70283, TotalOutputTokens: 55110},
 			},
 		},
 		{
@@ -264,13 +269,19 @@ func TestParse(t *testing.T) {
 				ContextWindow: struct {
 					ContextWindowSize int      `json:"context_window_size"`
 					UsedPercentage    *float64 `json:"used_percentage"`
+					TotalInputTokens  int      `json:"total_input_tokens"`  // session total
+					TotalOutputTokens int      `json:"total_output_tokens"` // session total
 					CurrentUsage      *struct {
+						InputTokens              int `json:"input_tokens"`
+						OutputTokens             int `json:"output_tokens"`
 						CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
 						CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 					} `json:"current_usage"`
 				}{
 					UsedPercentage: &pct,
 					CurrentUsage: &struct {
+						InputTokens              int `json:"input_tokens"`
+						OutputTokens             int `json:"output_tokens"`
 						CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
 						CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 					}{
@@ -339,6 +350,14 @@ func TestParse(t *testing.T) {
 					tt.want.ContextWindow.ContextWindowSize,
 				)
 			}
+			if got.ContextWindow.TotalInputTokens != tt.want.ContextWindow.TotalInputTokens ||
+				got.ContextWindow.TotalOutputTokens != tt.want.ContextWindow.TotalOutputTokens {
+				t.Errorf(
+					"TotalInputTokens, TotalOutputTokens = %d, %d, want %d, %d",
+					got.ContextWindow.TotalInputTokens, got.ContextWindow.TotalOutputTokens,
+					tt.want.ContextWindow.TotalInputTokens, tt.want.ContextWindow.TotalOutputTokens,
+				)
+			}
 			if tt.want.ContextWindow.UsedPercentage == nil {
 				if got.ContextWindow.UsedPercentage != nil {
 					t.Errorf("UsedPercentage = %v, want nil", *got.ContextWindow.UsedPercentage)
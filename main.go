@@ -2,18 +2,36 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"maps"
+	"math"
+	"net/url"
 	"os"
+	"path/filepath"
 	runtimedebug "runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/fredrikaverpil/claudeline/internal/budget"
+	"github.com/fredrikaverpil/claudeline/internal/burnrate"
+	"github.com/fredrikaverpil/claudeline/internal/cachehit"
+	"github.com/fredrikaverpil/claudeline/internal/config"
 	"github.com/fredrikaverpil/claudeline/internal/creds"
+	"github.com/fredrikaverpil/claudeline/internal/fillrate"
+	"github.com/fredrikaverpil/claudeline/internal/forge"
 	"github.com/fredrikaverpil/claudeline/internal/git"
+	"github.com/fredrikaverpil/claudeline/internal/history"
+	"github.com/fredrikaverpil/claudeline/internal/ledger"
 	"github.com/fredrikaverpil/claudeline/internal/paths"
 	"github.com/fredrikaverpil/claudeline/internal/render"
+	"github.com/fredrikaverpil/claudeline/internal/report"
 	"github.com/fredrikaverpil/claudeline/internal/status"
 	"github.com/fredrikaverpil/claudeline/internal/stdin"
 	"github.com/fredrikaverpil/claudeline/internal/update"
@@ -60,33 +78,25 @@ func currentVersion() string {
 	return ""
 }
 
-// config holds CLI configuration.
-type config struct {
-	showGitBranch   bool
-	gitBranchMaxLen int
-	showCwd         bool
-	cwdMaxLen       int
-	showCost        bool
-
-	// debug options
-	debug      bool
-	usageFile  string
-	statusFile string
-	updateFile string
+// subcommands are run by name as the first argument, e.g. "claudeline report".
+// Without one, claudeline renders the status line.
+var subcommands = map[string]func(args []string) int{
+	"report": runReport,
 }
 
 func runMain() int {
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			return cmd(os.Args[2:])
+		}
+	}
 	showVersion := flag.Bool("version", false, "print version and exit")
+	configPath := flag.String("config", "", "path to config file (default: first of "+
+		strings.Join(config.Candidates(configDir), ", ")+")")
+	flagCfg := config.Default()
+	config.Bind(flag.CommandLine, &flagCfg)
 	debugLogFile := paths.MustCacheFile(configDir, "debug.log")
-	debug := flag.Bool("debug", false, "write warnings and errors to "+debugLogFile)
-	showGitBranch := flag.Bool("git-branch", false, "show git branch in the status line")
-	gitBranchMaxLen := flag.Int("git-branch-max-len", 30, "max display length for git branch")
-	showCwd := flag.Bool("cwd", false, "show working directory name in the status line")
-	cwdMaxLen := flag.Int("cwd-max-len", 30, "max display length for working directory name")
-	showCost := flag.Bool("cost", false, "show estimated session cost in the status line (always on for API key users)")
-	usageFile := flag.String("usage-file", "", "read usage data from file instead of API")
-	statusFile := flag.String("status-file", "", "read status data from file instead of API")
-	updateFile := flag.String("update-file", "", "read update data from file instead of API")
+	flag.Lookup("debug").Usage = "write warnings and errors to " + debugLogFile
 	flag.Parse()
 
 	if *showVersion {
@@ -96,10 +106,12 @@ func runMain() int {
 		return 0
 	}
 
+	cfg, cfgErrs := loadConfig(*configPath, flag.CommandLine, os.Getenv)
+
 	log.SetPrefix("claudeline: ")
 	log.SetFlags(log.Ldate | log.Ltime)
 	_ = os.MkdirAll(paths.CacheDir(), 0o700)
-	if *debug {
+	if cfg.Debug {
 		// Truncate if over 1MB to prevent unbounded growth.
 		if info, err := os.Stat(debugLogFile); err == nil && info.Size() > 1024*1024 {
 			_ = os.Truncate(debugLogFile, 0)
@@ -112,18 +124,10 @@ func runMain() int {
 	} else {
 		log.SetOutput(io.Discard)
 	}
-
-	cfg := config{
-		debug:           *debug,
-		showGitBranch:   *showGitBranch,
-		gitBranchMaxLen: *gitBranchMaxLen,
-		showCwd:         *showCwd,
-		cwdMaxLen:       *cwdMaxLen,
-		showCost:        *showCost,
-		usageFile:       *usageFile,
-		statusFile:      *statusFile,
-		updateFile:      *updateFile,
+	for _, err := range cfgErrs {
+		log.Printf("config: %v", err)
 	}
+
 	if err := run(cfg); err != nil {
 		fmt.Fprintf(os.Stderr, "claudeline: %v\n", err)
 		return 1
@@ -131,57 +135,259 @@ func runMain() int {
 	return 0
 }
 
-func run(cfg config) error {
+// runReport prints a usage summary from the history recorded by renders.
+func runReport(args []string) int {
+	fs := flag.NewFlagSet("claudeline report", flag.ContinueOnError)
+	since := fs.String("since", "7d", "report start: days or weeks ago (e.g. 7d, 2w), a duration (e.g. 12h) or a date (2006-01-02)")
+	by := fs.String("by", "day", "group by: "+strings.Join(report.Groupings, ", "))
+	format := fs.String("format", "text", "output format: "+strings.Join(report.Formats, ", "))
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	start, err := report.ParseSince(*since, time.Now())
+	if err == nil {
+		err = reportTo(os.Stdout, paths.MustStateFile(configDir, "history.jsonl"), start, *by, *format)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "claudeline report: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// reportTo writes the report of the history at path from start to w.
+func reportTo(w io.Writer, path string, start time.Time, by, format string) error {
+	entries, err := history.Read(path, start.Add(-report.Baseline))
+	if err != nil {
+		return err
+	}
+	rows, err := report.Build(entries, start, by)
+	if err != nil {
+		return err
+	}
+	return report.Write(w, rows, by, format)
+}
+
+// loadConfig layers the config file, CLAUDELINE_* environment variables from
+// getenv and the command-line flags explicitly set in flags on top of the
+// defaults. Problems are returned for the debug log rather than failing the
+// render; an invalid setting falls back to its default.
+func loadConfig(path string, flags *flag.FlagSet, getenv func(string) string) (config.Config, []error) {
+	if path == "" {
+		path = getenv(config.EnvName("config"))
+	}
+	if path == "" {
+		path = config.Find(configDir)
+	}
+
+	var errs []error
+	cfg, err := config.Load(path)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if err := cfg.ApplyEnv(getenv); err != nil {
+		errs = append(errs, err)
+	}
+	if err := cfg.ApplyFlags(flags); err != nil {
+		errs = append(errs, err)
+	}
+	if err := render.ValidateLayout(cfg.Layout); err != nil {
+		errs = append(errs, fmt.Errorf("layout: %w", err))
+		cfg.Layout = nil
+	}
+	for i, line := range cfg.Lines {
+		if err := render.ValidateLayout(line); err != nil {
+			errs = append(errs, fmt.Errorf("lines[%d]: %w", i, err))
+			cfg.Lines = nil
+			break
+		}
+	}
+	if err := render.ValidateLayout(slices.Collect(maps.Keys(cfg.Priorities))); err != nil {
+		errs = append(errs, fmt.Errorf("priorities: %w", err))
+		cfg.Priorities = nil
+	}
+	if _, err := render.ParseBarStyle(cfg.ContextBar, 1); err != nil {
+		errs = append(errs, fmt.Errorf("context_bar: %w", err))
+		cfg.ContextBar = ""
+	}
+	if _, err := render.ParseBarStyle(cfg.QuotaBar, 1); err != nil {
+		errs = append(errs, fmt.Errorf("quota_bar: %w", err))
+		cfg.QuotaBar = ""
+	}
+	if cfg.Color != "" && cfg.Color != "auto" {
+		if _, err := render.ParseColorDepth(cfg.Color); err != nil {
+			errs = append(errs, fmt.Errorf("color: %w", err))
+			cfg.Color = ""
+		}
+	}
+	if _, err := render.LoadTheme(cfg.Theme, cfg.Themes, render.DepthTrueColor); err != nil {
+		errs = append(errs, err)
+		cfg.Theme = ""
+		cfg.Themes = nil
+	}
+	if err := forge.Validate(cfg.ForgeURLs); err != nil {
+		errs = append(errs, err)
+		cfg.ForgeURLs = nil
+	}
+	if cfg.Format != "" {
+		if err := render.ValidateFormat(cfg.Format); err != nil {
+			errs = append(errs, err)
+			cfg.Format = ""
+		}
+	}
+	if err := cfg.Repair(); err != nil {
+		errs = append(errs, fmt.Errorf("%w (using defaults)", err))
+	}
+	return cfg, errs
+}
+
+func run(cfg config.Config) error {
 	ctx := context.Background()
 
 	data, err := readStdin(cfg)
 	if err != nil {
 		return err
 	}
-	debugMode := cfg.usageFile != "" && cfg.statusFile != ""
+	debugMode := cfg.UsageFile != "" && cfg.StatusFile != ""
 	cred, loginType, isProvider := creds.Resolve(ctx, debugMode, configDir)
 	remote := fetchRemoteData(ctx, cfg, cred, loginType, isProvider)
 
-	cacheMiss := false
-	if cu := data.ContextWindow.CurrentUsage; cu != nil {
-		cacheMiss = cu.CacheReadInputTokens == 0 && cu.CacheCreationInputTokens > 0
+	theme := loadTheme(cfg)
+
+	cache := cacheStats(cfg, data, debugMode)
+	showBranch := branchShown(cfg)
+	var repo git.Repo
+	if showBranch {
+		repo, _ = git.Discover(data.Cwd, os.Getenv)
+	}
+	ab := aheadBehind(ctx, cfg, repo)
+	sample, recorded := recordHistory(cfg, data, remote.usage, cache.miss, debugMode)
+	totals := costTotals(cfg, data, sample, debugMode)
+	branch := repo.Branch()
+	var detached, branchURL string
+	if showBranch && branch == "" {
+		detached = repo.Detached()
+	}
+	// Links are dropped without color, so the remote is not looked up.
+	if showBranch && branch != "" && theme.Depth != render.DepthNone {
+		branchURL = forge.BranchURL(repo.RemoteURL("origin"), branch, cfg.ForgeURLs)
 	}
 
 	output := render.Build(render.Params{
 		LoginType:          loginType,
 		Model:              data.Model.DisplayName,
+		ModelMaxLen:        cfg.ModelMaxLen,
 		ContextUsedPct:     data.ContextWindow.UsedPercentage,
 		ContextWindowSize:  data.ContextWindow.ContextWindowSize,
 		CompactWindow:      os.Getenv("CLAUDE_CODE_AUTO_COMPACT_WINDOW"),
 		CompactPctOverride: os.Getenv("CLAUDE_AUTOCOMPACT_PCT_OVERRIDE"),
 		Exceeds200kTokens:  data.Exceeds200kTokens,
-		CacheMiss:          cacheMiss,
+		CompactTurns:       compactTurns(cfg, data, debugMode),
+		ContextTokens:      cfg.ContextTokens,
+		CacheMiss:          cache.miss,
+		CacheMisses:        cache.misses,
+		ShowCacheHit:       cfg.ShowCacheHit || cfg.UsesSegment(render.SegmentCache),
+		CacheHitPct:        cache.hitPct,
 		Usage:              remote.usage,
 		StdinRateLimits:    data.RateLimits,
+		FiveHourExhausted:  fiveHourExhausted(cfg, data, remote.usage, debugMode),
 		SubscriptionType:   cred.ClaudeAiOauth.SubscriptionType,
 		Status:             remote.status,
 		Update:             remote.update,
-		ShowCwd:            cfg.showCwd,
+		ShowCwd:            cfg.ShowCwd || cfg.UsesSegment(render.SegmentCwd),
 		Cwd:                data.Cwd,
-		CwdMaxLen:          cfg.cwdMaxLen,
-		ShowBranch:         cfg.showGitBranch,
-		Branch:             git.Branch(),
-		BranchMaxLen:       cfg.gitBranchMaxLen,
-		ShowCost:           cfg.showCost || loginType == creds.ProviderAPI,
+		CwdURL:             fileURL(data.Cwd),
+		CwdMaxLen:          cfg.CwdMaxLen,
+		ShowBranch:         showBranch,
+		Branch:             branch,
+		BranchURL:          branchURL,
+		DetachedHead:       detached,
+		GitStatus:          gitStatus(ctx, cfg, repo),
+		GitOperation:       repo.Operation(),
+		GitAhead:           ab.ahead,
+		GitBehind:          ab.behind,
+		BranchMaxLen:       cfg.GitBranchMaxLen,
+		ShowCost:           cfg.ShowCost || loginType == creds.ProviderAPI || cfg.UsesSegment(render.SegmentCost),
 		CostUSD:            data.Cost.TotalCostUSD,
+		ShowProjectCost:    cfg.ShowProjectCost || cfg.UsesSegment(render.SegmentProjectCost),
+		ProjectCostUSD:     totals.Project,
+		BudgetPct:          budgetPct(cfg, totals, recorded),
+		Layout:             cfg.Layout,
+		Lines:              cfg.Lines,
+		Format:             cfg.Format,
+		MaxWidth:           maxWidth(cfg),
+		Priorities:         cfg.Priorities,
+		Theme:              &theme,
+		Stdin:              data,
 	})
 
 	_, err = fmt.Fprintln(os.Stdout, output)
 	return err
 }
 
+// loadTheme resolves the configured theme for the terminal and applies the
+// bar, zone and glyph settings on top of it.
+func loadTheme(cfg config.Config) render.Theme {
+	theme, err := render.LoadTheme(cfg.Theme, cfg.Themes, colorDepth(cfg))
+	if err != nil {
+		theme = render.DefaultTheme // already reported by loadConfig
+	}
+	theme.ContextBar, _ = render.ParseBarStyle(cfg.ContextBar, cfg.ContextBarWidth)
+	theme.QuotaBar, _ = render.ParseBarStyle(cfg.QuotaBar, cfg.QuotaBarWidth)
+	if cfg.ContextZones != nil {
+		theme.ContextZones = [2]int(cfg.ContextZones)
+	}
+	if cfg.QuotaZones != nil {
+		theme.QuotaZones = [2]int(cfg.QuotaZones)
+		theme.ModelZones = theme.QuotaZones
+	}
+	if cfg.ModelZones != nil {
+		theme.ModelZones = [2]int(cfg.ModelZones)
+	}
+	if cfg.BudgetZones != nil {
+		theme.BudgetZones = [2]int(cfg.BudgetZones)
+	}
+	if cfg.ASCII {
+		// Only the block style has an ASCII form.
+		theme.Glyphs = render.ASCIIGlyphs
+		theme.ContextBar.Name = "block"
+		theme.QuotaBar.Name = "block"
+	}
+	return theme
+}
+
+// colorDepth returns the configured color depth, or the depth detected from
+// NO_COLOR, COLORTERM and TERM when unset or "auto".
+func colorDepth(cfg config.Config) render.ColorDepth {
+	if depth, err := render.ParseColorDepth(cfg.Color); err == nil {
+		return depth
+	}
+	return render.DetectColorDepth(os.Getenv)
+}
+
+// maxWidth returns the configured max line width, falling back to the
+// terminal width in $COLUMNS. Returns 0 (unlimited) when neither is set.
+func maxWidth(cfg config.Config) int {
+	if cfg.MaxWidth > 0 {
+		return cfg.MaxWidth
+	}
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		return cols
+	}
+	return 0
+}
+
 // readStdin reads and parses the stdin JSON payload.
-func readStdin(cfg config) (stdin.Data, error) {
+func readStdin(cfg config.Config) (stdin.Data, error) {
 	input, err := io.ReadAll(os.Stdin)
 	if err != nil {
 		return stdin.Data{}, fmt.Errorf("read stdin: %w", err)
 	}
-	if cfg.debug {
+	if cfg.Debug {
 		_ = os.WriteFile(paths.MustCacheFile(configDir, "stdin.json"), input, 0o600)
 	}
 	data, err := stdin.Parse(input)
@@ -191,6 +397,252 @@ func readStdin(cfg config) (stdin.Data, error) {
 	return data, nil
 }
 
+// fiveHourExhausted records the current 5-hour quota utilization and returns
+// when the quota runs out at the current burn rate. Returns the zero time
+// when there is no projection or the history is disabled. Debug mode reads
+// fixtures, so it doesn't touch the history.
+func fiveHourExhausted(cfg config.Config, data stdin.Data, resp *usage.Response, debugMode bool) time.Time {
+	if cfg.NoHistory || debugMode {
+		return time.Time{}
+	}
+	q := quotas(data, resp)
+	if q.fiveHour == nil {
+		return time.Time{}
+	}
+	s := burnrate.Sample{Time: time.Now().Unix(), Pct: *q.fiveHour, ResetsAt: q.fiveHourReset}
+	t, ok := burnrate.Project(burnrate.Record(paths.MustCacheFile(configDir, "burnrate.json"), s))
+	if !ok {
+		return time.Time{}
+	}
+	return t
+}
+
+// recordHistory appends the render's sample to the usage history and
+// returns it. Returns false when the history is disabled or in debug mode.
+func recordHistory(cfg config.Config, data stdin.Data, resp *usage.Response, cacheMiss, debugMode bool) (history.Entry, bool) {
+	if cfg.NoHistory || debugMode {
+		return history.Entry{}, false
+	}
+	q := quotas(data, resp)
+	e := history.Entry{
+		Time:      time.Now().Unix(),
+		SessionID: data.SessionID,
+		Project:   projectDir(data),
+		Model:     data.Model.DisplayName,
+		Context:   data.ContextWindow.UsedPercentage,
+		CacheMiss: cacheMiss,
+		FiveHour:  q.fiveHour,
+		SevenDay:  q.sevenDay,
+		CostUSD:   data.Cost.TotalCostUSD,
+	}
+	if ctx := data.ContextWindow.UsedPercentage; ctx != nil {
+		warnPct := render.ContextWarnPct(os.Getenv("CLAUDE_CODE_AUTO_COMPACT_WINDOW"),
+			data.ContextWindow.ContextWindowSize, os.Getenv("CLAUDE_AUTOCOMPACT_PCT_OVERRIDE"))
+		e.Warn = int(math.Round(*ctx)) >= warnPct
+	}
+	err := history.Append(paths.MustStateFile(configDir, "history.jsonl"), e)
+	if err != nil {
+		log.Printf("history: %v", err)
+	}
+	return e, true
+}
+
+// budgetPct returns the highest consumption of the configured cost budgets
+// in percent, from the cost totals kept in the project cost ledger. Returns 0
+// when no budget is set or the history is disabled.
+func budgetPct(cfg config.Config, totals ledger.Totals, ok bool) int {
+	limits := budget.Limits{Daily: cfg.BudgetDaily, Weekly: cfg.BudgetWeekly, Project: cfg.BudgetProject}
+	if !limits.Any() || !ok {
+		return 0
+	}
+	return budget.Pct(limits, budget.Spend{Day: totals.Day, Week: totals.Week, Project: totals.Project})
+}
+
+// branchShown reports whether the git branch can appear in the output, either
+// as the branch segment or through a format template. All git data hangs off
+// the branch, so the repository is only read when it is shown.
+func branchShown(cfg config.Config) bool {
+	return cfg.ShowGitBranch || cfg.UsesSegment(render.SegmentBranch) || cfg.Format != ""
+}
+
+// gitStatus returns the working tree state when enabled, or nil.
+func gitStatus(ctx context.Context, cfg config.Config, repo git.Repo) *git.Status {
+	if !cfg.GitStatus || !branchShown(cfg) || repo.WorkTree == "" {
+		return nil
+	}
+	st, err := repo.Status(ctx, paths.MustCacheFile(configDir, "gitstatus.json"))
+	if err != nil {
+		log.Printf("git: %v", err)
+		return nil
+	}
+	return &st
+}
+
+// fileURL returns a file:// URL for a local directory, with the host name so
+// that terminals can tell local from remote paths. Returns "" for "".
+func fileURL(path string) string {
+	if path == "" {
+		return ""
+	}
+	p := filepath.ToSlash(path)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p // Windows drive letter, e.g. /C:/src
+	}
+	host, _ := os.Hostname()
+	return (&url.URL{Scheme: "file", Host: host, Path: p}).String()
+}
+
+// divergence is how far the branch has moved from its upstream.
+type divergence struct {
+	ahead, behind int
+}
+
+// aheadBehind returns the commits ahead of and behind the branch's upstream
+// when enabled.
+func aheadBehind(ctx context.Context, cfg config.Config, repo git.Repo) divergence {
+	if !cfg.GitAheadBehind || !branchShown(cfg) || repo.GitDir == "" {
+		return divergence{}
+	}
+	ahead, behind, err := repo.AheadBehind(ctx, paths.MustCacheFile(configDir, "aheadbehind.json"))
+	if err != nil {
+		if !errors.Is(err, git.ErrNoUpstream) {
+			log.Printf("git: %v", err)
+		}
+		return divergence{}
+	}
+	return divergence{ahead: ahead, behind: behind}
+}
+
+// cacheEfficiency is the prompt cache behavior of the last turn and of the
+// session so far.
+type cacheEfficiency struct {
+	miss   bool // the last turn missed the cache
+	misses int  // consecutive misses in the session; 0 when untracked
+	hitPct *int // session cache hit ratio in percent; nil when unknown
+}
+
+// cacheStats records the last turn's cache usage for the session and returns
+// the session's cache efficiency. Only the last turn is known when recording
+// is off.
+func cacheStats(cfg config.Config, data stdin.Data, debugMode bool) cacheEfficiency {
+	cu := data.ContextWindow.CurrentUsage
+	if cu == nil {
+		return cacheEfficiency{}
+	}
+	u := cachehit.Usage{
+		Input:      cu.InputTokens,
+		CacheRead:  cu.CacheReadInputTokens,
+		CacheWrite: cu.CacheCreationInputTokens,
+	}
+	c := cacheEfficiency{miss: u.Miss()}
+	if cfg.NoHistory || debugMode || data.SessionID == "" {
+		return c
+	}
+	s, err := cachehit.Record(paths.MustCacheFile(configDir, "cachehit.json"), data.SessionID, u, time.Now())
+	if err != nil {
+		log.Printf("cache hit: %v", err)
+	}
+	c.misses = s.Misses
+	if pct, ok := s.HitPct(); ok {
+		c.hitPct = &pct
+	}
+	return c
+}
+
+// compactTurns records the session's context growth and estimates the turns
+// left before auto-compaction. Returns nil when unknown or when recording is
+// off.
+func compactTurns(cfg config.Config, data stdin.Data, debugMode bool) *int {
+	pct := data.ContextWindow.UsedPercentage
+	if cfg.NoHistory || debugMode || pct == nil || data.SessionID == "" {
+		return nil
+	}
+	s, err := fillrate.Record(paths.MustCacheFile(configDir, "fillrate.json"), data.SessionID, *pct, time.Now())
+	if err != nil {
+		log.Printf("fill rate: %v", err)
+	}
+	threshold := render.CompactPct(os.Getenv("CLAUDE_CODE_AUTO_COMPACT_WINDOW"),
+		data.ContextWindow.ContextWindowSize, os.Getenv("CLAUDE_AUTOCOMPACT_PCT_OVERRIDE"))
+	n, ok := s.TurnsUntil(float64(threshold))
+	if !ok {
+		return nil
+	}
+	return &n
+}
+
+// costTotals records the session's cost in the project cost ledger and
+// returns the project's cumulative cost and the day's and week's cost, or
+// zeros when unknown. A ledger that doesn't track the day and week yet is
+// seeded once from the usage history up to and including sample.
+func costTotals(cfg config.Config, data stdin.Data, sample history.Entry, debugMode bool) ledger.Totals {
+	project := projectDir(data)
+	if cfg.NoHistory || debugMode || project == "" || data.SessionID == "" {
+		return ledger.Totals{}
+	}
+	seed := func(day, week time.Time) (float64, float64) {
+		entries, err := history.Read(paths.MustStateFile(configDir, "history.jsonl"), week.Add(-report.Baseline))
+		if err != nil {
+			log.Printf("ledger: %v", err)
+		}
+		// The sample may have been skipped as a duplicate or rate limited.
+		entries = append(entries, sample)
+		return report.Cost(entries, day), report.Cost(entries, week)
+	}
+	totals, err := ledger.Update(paths.MustStateFile(configDir, "ledger.json"),
+		project, data.SessionID, data.Cost.TotalCostUSD, time.Now(), seed)
+	if err != nil {
+		log.Printf("ledger: %v", err)
+	}
+	return totals
+}
+
+// projectDir returns the workspace project directory, falling back to the
+// working directory.
+func projectDir(data stdin.Data) string {
+	if data.Workspace.ProjectDir != "" {
+		return data.Workspace.ProjectDir
+	}
+	return data.Cwd
+}
+
+// quotaUsage holds the aggregate quota utilization in percent, nil when
+// unavailable.
+type quotaUsage struct {
+	fiveHour      *float64
+	fiveHourReset int64 // Unix seconds; 0 when unknown
+	sevenDay      *float64
+}
+
+// quotas returns the aggregate quota utilization from stdin rate_limits,
+// falling back to the usage API.
+func quotas(data stdin.Data, resp *usage.Response) quotaUsage {
+	var q quotaUsage
+	if rl := data.RateLimits; rl != nil {
+		if rl.FiveHour != nil && rl.FiveHour.UsedPercentage != nil {
+			q.fiveHour = rl.FiveHour.UsedPercentage
+			if rl.FiveHour.ResetsAt != nil {
+				q.fiveHourReset = int64(*rl.FiveHour.ResetsAt)
+			}
+		}
+		if rl.SevenDay != nil {
+			q.sevenDay = rl.SevenDay.UsedPercentage
+		}
+	}
+	if resp == nil {
+		return q
+	}
+	if q.fiveHour == nil && resp.FiveHour != nil {
+		q.fiveHour = &resp.FiveHour.Utilization
+		if t, err := time.Parse(time.RFC3339, resp.FiveHour.ResetsAt); err == nil {
+			q.fiveHourReset = t.Unix()
+		}
+	}
+	if q.sevenDay == nil && resp.SevenDay != nil {
+		q.sevenDay = &resp.SevenDay.Utilization
+	}
+	return q
+}
+
 // remoteData holds responses from concurrent API calls.
 type remoteData struct {
 	usage  *usage.Response
@@ -201,7 +653,7 @@ type remoteData struct {
 // fetchRemoteData fetches usage, status, and update data concurrently.
 func fetchRemoteData(
 	ctx context.Context,
-	cfg config,
+	cfg config.Config,
 	cred creds.Credentials,
 	loginType string,
 	isProvider bool,
@@ -213,8 +665,8 @@ func fetchRemoteData(
 	if !isProvider {
 		token := cred.ClaudeAiOauth.AccessToken
 		switch {
-		case cfg.usageFile != "":
-			resp, err := usage.ReadResponse(cfg.usageFile)
+		case cfg.UsageFile != "":
+			resp, err := usage.ReadResponse(cfg.UsageFile)
 			if err != nil {
 				log.Printf("usage: read file: %v", err)
 			}
@@ -227,8 +679,8 @@ func fetchRemoteData(
 	}
 
 	if !creds.IsThirdPartyProvider(loginType) {
-		if cfg.statusFile != "" {
-			resp, err := status.ReadResponse(cfg.statusFile)
+		if cfg.StatusFile != "" {
+			resp, err := status.ReadResponse(cfg.StatusFile)
 			if err != nil {
 				log.Printf("status: read file: %v", err)
 			}
@@ -238,8 +690,8 @@ func fetchRemoteData(
 		}
 	}
 
-	if cfg.updateFile != "" {
-		resp, err := update.ReadResponse(cfg.updateFile)
+	if cfg.UpdateFile != "" {
+		resp, err := update.ReadResponse(cfg.UpdateFile)
 		if err != nil {
 			log.Printf("update: read file: %v", err)
 		}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"flag"
 	"os"
+	"path/filepath"
+	"slices"
 	"testing"
 
+	"github.com/fredrikaverpil/claudeline/internal/config"
 	"github.com/fredrikaverpil/claudeline/internal/creds"
 	"github.com/fredrikaverpil/claudeline/internal/paths"
 )
@@ -29,12 +33,9 @@ func BenchmarkRun(b *testing.B) {
 		b.Fatalf("read stdin testdata: %v", err)
 	}
 
-	cfg := config{
-		usageFile:       usageFile,
-		statusFile:      statusFile,
-		gitBranchMaxLen: 30,
-		cwdMaxLen:       30,
-	}
+	cfg := config.Default()
+	cfg.UsageFile = usageFile
+	cfg.StatusFile = statusFile
 
 	// Discard stdout to avoid benchmark noise.
 	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
@@ -64,3 +65,37 @@ func BenchmarkRun(b *testing.B) {
 		r.Close()
 	}
 }
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "claudeline.json")
+	if err := os.WriteFile(path, []byte(`{"cwd": true, "layout": ["context", "cwd"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"CLAUDELINE_CWD_MAX_LEN": "0"}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagCfg := config.Default()
+	config.Bind(fs, &flagCfg)
+	if err := fs.Parse([]string{
+		"-color", "none",
+		"-usage-file", "usage.json",
+		"-context-zones", "70,60",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, errs := loadConfig(path, fs, func(k string) string { return env[k] })
+	if len(errs) != 1 {
+		t.Fatalf("loadConfig() errors = %v, want one", errs)
+	}
+	// The invalid settings fall back to their defaults ...
+	if cfg.CwdMaxLen != 30 || cfg.ContextZones != nil {
+		t.Errorf("invalid settings = %d, %v, want 30, nil", cfg.CwdMaxLen, cfg.ContextZones)
+	}
+	// ... and the valid ones are kept.
+	if !cfg.ShowCwd || !slices.Equal(cfg.Layout, []string{"context", "cwd"}) ||
+		cfg.Color != "none" || cfg.UsageFile != "usage.json" {
+		t.Errorf("loadConfig() = %+v, want the file, environment and flag settings kept", cfg)
+	}
+}